@@ -0,0 +1,88 @@
+package bcast
+
+import (
+	"context"
+	"sync"
+)
+
+// AckMessage is delivered to members in place of the payload of
+// Group.SendAcked. Members confirm they processed it with Ack.
+type AckMessage struct {
+	Payload interface{}
+	acks    *Acks
+}
+
+// Ack confirms the message was processed. All members share the same
+// *AckMessage, so each of them must call Ack exactly once.
+func (m *AckMessage) Ack() {
+	m.acks.ack()
+}
+
+// Acks tracks the acknowledgments of a message sent with SendAcked.
+type Acks struct {
+	report *DeliveryReport
+	lock   sync.Mutex
+	acked  int
+	notify chan struct{}
+}
+
+// SendAcked broadcasts val to every member as an *AckMessage and
+// returns the tracker of their acknowledgments.
+func (g *Group) SendAcked(val interface{}) *Acks {
+	acks := &Acks{notify: make(chan struct{}, 1)}
+	message := g.data(nil, &AckMessage{Payload: val, acks: acks})
+	message.report = newDeliveryReport()
+	acks.report = message.report
+	g.in <- message
+	return acks
+}
+
+// Acked returns the number of acknowledgments so far.
+func (a *Acks) Acked() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.acked
+}
+
+// Wait blocks until every member that received the message
+// acknowledged it. It returns ctx.Err() when ctx is done first.
+func (a *Acks) Wait(ctx context.Context) error {
+	return a.wait(ctx, -1)
+}
+
+// WaitN blocks until n members acknowledged the message, or all of
+// them when fewer received it. It returns ctx.Err() when ctx is done
+// first.
+func (a *Acks) WaitN(ctx context.Context, n int) error {
+	return a.wait(ctx, n)
+}
+
+func (a *Acks) wait(ctx context.Context, n int) error {
+	delivered := a.report.done
+	for {
+		acked := a.Acked()
+		if n >= 0 && acked >= n {
+			return nil
+		}
+		if delivered == nil && acked >= a.report.Delivered() {
+			return nil
+		}
+		select {
+		case <-a.notify:
+		case <-delivered:
+			delivered = nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (a *Acks) ack() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.acked++
+	select {
+	case a.notify <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,35 @@
+package bcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Create new broadcast group with two acknowledging members.
+// Wait for the first acknowledgment and then for all of them.
+func TestSendAcked(t *testing.T) {
+	group := NewGroup()
+	members := []*Member{group.Join(), group.Join()}
+	go group.Broadcast(0)
+
+	acks := group.SendAcked("work")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	members[0].Recv().(*AckMessage).Ack()
+	if err := acks.WaitN(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	short, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	if err := acks.Wait(short); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	members[1].Recv().(*AckMessage).Ack()
+	if err := acks.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if acks.Acked() != 2 {
+		t.Fatalf("expected 2 acknowledgments, got %d", acks.Acked())
+	}
+}
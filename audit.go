@@ -0,0 +1,74 @@
+package bcast
+
+// AuditEvent is the kind of an AuditRecord.
+type AuditEvent int
+
+const (
+	// AuditSent records a message stamped by the broadcast loop.
+	AuditSent AuditEvent = iota
+	// AuditDelivered records a message a member received.
+	AuditDelivered
+	// AuditDropped records a message a member dropped or lost by
+	// leaving.
+	AuditDropped
+)
+
+// AuditRecord is one entry of the audit stream of a group. Members are
+// identified by their IDs, zero stands for the group itself.
+type AuditRecord struct {
+	Event  AuditEvent
+	Seq    int
+	Sender uint64
+	Member uint64
+	// Payload is only set for groups auditing payloads.
+	Payload interface{}
+}
+
+// WithAudit streams who sent which message and which members received
+// or dropped it to sink, e.g. to write a compliance log. Payloads are
+// left out unless payloads is set. Messages a member is not meant to
+// get are not recorded for it. The sink is called by the broadcast
+// loop and by the member listeners, so it must be quick and safe for
+// concurrent use.
+func WithAudit(sink func(AuditRecord), payloads bool) Option {
+	return func(g *Group) {
+		g.audit = &auditor{sink: sink, payloads: payloads}
+	}
+}
+
+type auditor struct {
+	sink     func(AuditRecord)
+	payloads bool
+}
+
+func (a *auditor) sent(message *Message) {
+	if message.msg_type != MSG_TYPE_DATA {
+		return
+	}
+	a.sink(a.record(AuditSent, message, nil))
+}
+
+func (a *auditor) delivery(m *Member, message *Message, delivered bool) {
+	if message.msg_type != MSG_TYPE_DATA {
+		return
+	}
+	event := AuditDropped
+	if delivered {
+		event = AuditDelivered
+	}
+	a.sink(a.record(event, message, m))
+}
+
+func (a *auditor) record(event AuditEvent, message *Message, m *Member) AuditRecord {
+	record := AuditRecord{Event: event, Seq: message.clock}
+	if message.sender != nil {
+		record.Sender = message.sender.id
+	}
+	if m != nil {
+		record.Member = m.id
+	}
+	if a.payloads {
+		record.Payload = message.payload
+	}
+	return record
+}
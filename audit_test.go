@@ -0,0 +1,49 @@
+package bcast
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Create new audited broadcast group with a reading and a leaving
+// member and check the audit stream of one message.
+func TestAudit(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		records []AuditRecord
+	)
+	group := NewGroup(WithAudit(func(record AuditRecord) {
+		lock.Lock()
+		records = append(records, record)
+		lock.Unlock()
+	}, false))
+	reader := group.Join()
+	leaving := group.Join()
+	go group.Broadcast(0)
+
+	report := group.SendTracked("audited")
+	if val := reader.Recv(); val != "audited" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	leaving.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := report.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	events := make(map[AuditEvent]uint64)
+	for _, record := range records {
+		if record.Payload != nil {
+			t.Fatal("payload must not be audited")
+		}
+		events[record.Event] = record.Member
+	}
+	if len(records) != 3 || events[AuditDelivered] != reader.ID() || events[AuditDropped] != leaving.ID() {
+		t.Fatalf("unexpected audit records %+v", records)
+	}
+}
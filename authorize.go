@@ -0,0 +1,66 @@
+package bcast
+
+import (
+	"fmt"
+	"log"
+)
+
+// Authorizer decides whether sender may send message to the group.
+// The sender is nil for values sent to the group directly. A non-nil
+// error rejects the message.
+type Authorizer func(sender *Member, message Message) error
+
+// AuthError tells a member that one of its messages was rejected by
+// the authorizer of the group. It is delivered wrapped in an
+// *ErrorPayload like other in-band errors.
+type AuthError struct {
+	Payload interface{}
+	Err     error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("bcast: message rejected: %v", e.Err)
+}
+
+// Unwrap returns the error of the authorizer.
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// WithAuthorizer checks every value sent to the group with authorize
+// before the middleware runs, so business rules such as quotas or bans
+// are enforced in one place. A rejected message is dropped; a sending
+// member receives an *AuthError in its place, in order with the other
+// messages, while rejections of values sent to the group directly are
+// logged.
+func WithAuthorizer(authorize Authorizer) Option {
+	return func(g *Group) {
+		g.authorize = authorize
+	}
+}
+
+// authorized runs the authorizer on message. It returns a rejection
+// notice for the sender of a rejected message, if any, and whether the
+// message may be broadcast.
+func (g *Group) authorized(message Message) (Message, bool) {
+	var err error
+	if g.guard("authorizer", func() { err = g.authorize(message.sender, message) }) {
+		g.isolate(message.sender)
+		if err == nil {
+			err = fmt.Errorf("authorizer panicked")
+		}
+	}
+	if err == nil {
+		return message, true
+	}
+	message.report.expect(0)
+	if message.sender == nil {
+		log.Printf("bcast: message rejected: %v", err)
+		return Message{}, false
+	}
+	return Message{
+		msg_type: MSG_TYPE_ERROR,
+		payload:  &AuthError{Payload: message.payload, Err: err},
+		to:       message.sender,
+	}, false
+}
@@ -0,0 +1,34 @@
+package bcast
+
+import (
+	"errors"
+	"testing"
+)
+
+// Create new broadcast group banning one member.
+// Check that its message is dropped and it learns about the rejection.
+func TestAuthorizer(t *testing.T) {
+	errBanned := errors.New("banned")
+	var banned *Member
+	group := NewGroup(WithAuthorizer(func(sender *Member, message Message) error {
+		if sender != nil && sender == banned {
+			return errBanned
+		}
+		return nil
+	}))
+	banned = group.Join()
+	listener := group.Join()
+	go group.Broadcast(0)
+
+	go func() {
+		banned.Send("spam")
+		group.Send("allowed")
+	}()
+	if val := listener.Recv(); val != "allowed" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	var authErr *AuthError
+	if err, ok := banned.Recv().(error); !ok || !errors.As(err, &authErr) || authErr.Payload != "spam" || !errors.Is(err, errBanned) {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+}
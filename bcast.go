@@ -11,51 +11,192 @@ package bcast
 
 import (
 	"container/heap"
+	"context"
 	"errors"
+	"log"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Tier defines how a member is treated under backpressure.
+type Tier int32
+
+const (
+	// TierCritical members get every message however slow they
+	// read. It is the default.
+	TierCritical Tier = iota
+	// TierBestEffort members shed messages while their backlog
+	// exceeds bestEffortBacklog.
+	TierBestEffort
+)
+
+// bestEffortBacklog is the number of undelivered messages after which
+// best-effort members start to shed.
+const bestEffortBacklog = 32
+
+// smallGroup is the largest number of members for which the fan-out
+// tries a direct hand-over before falling back to goroutines.
+const smallGroup = 8
+
+// maxBatch limits the number of messages the broadcast loop takes from
+// its input at once.
+const maxBatch = 64
+
 const (
 	MSG_TYPE_DATA int = iota
 	MSG_TYPE_CLOSE
+	MSG_TYPE_ERROR
+	MSG_TYPE_EOS
+	MSG_TYPE_CONFIG
 )
 
+// EOS is delivered to members after CloseSend was called on their
+// group. No more values follow it.
+var EOS = errors.New("bcast: end of stream")
+
+// ErrTimeout is returned by RecvTimeout when no value arrived in time.
+var ErrTimeout = errors.New("bcast: receive timed out")
+
 // Message is an internal structure to pack messages together with
 // info about sender.
 type Message struct {
-	msg_type int
-	sender   *Member
-	payload  interface{}
-	clock    int
+	msg_type  int
+	sender    *Member
+	payload   interface{}
+	clock     int
+	droppable bool
+	report    *DeliveryReport
+	topic     string
+	to        *Member
+	tag       string
+	dist      Distribution
+	shed      bool
+	key       string
+	hops      []*Group
+	replayed  bool
+	filtered  bool
+	group     *Group
+}
+
+// ErrorPayload is delivered to members in place of a payload when an
+// error was sent in-band with SendError. It unwraps to the original
+// error so receivers may inspect it with errors.Is and errors.As.
+type ErrorPayload struct {
+	Err error
+}
+
+func (e *ErrorPayload) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error passed to SendError.
+func (e *ErrorPayload) Unwrap() error {
+	return e.Err
+}
+
+// Envelope carries a payload together with metadata. It is sent with
+// SendEnvelope and wraps the payloads delivered by groups using the
+// NilWrap policy, so a nil payload can't be taken for a control value.
+type Envelope struct {
+	Payload interface{}
+	// Headers are set by the sender, e.g. routing keys or trace IDs.
+	Headers map[string]string
+	// Time is when the envelope was sent.
+	Time time.Time
+	// Seq is the clock the group stamped the envelope with; it grows
+	// by one with every message broadcast to the group.
+	Seq int
 }
 
 // Member represents member of a Broadcast group.
 type Member struct {
-	group        *Group
-	Read         chan interface{}
-	clock        int
-	messageQueue PriorityQueue
-	send         chan Message
-	close        chan bool
+	group         *Group
+	Read          chan interface{}
+	clock         int
+	messageQueue  PriorityQueue
+	queueCap      int
+	send          chan Message
+	resync        chan bool
+	close         chan bool
+	tier          atomic.Int32
+	backlog       atomic.Int64
+	stash         []interface{}
+	stashLock     sync.Mutex
+	listenLock    sync.Mutex
+	listening     bool
+	inbound       int
+	topics        map[string]bool
+	tags          map[string]bool
+	topicLock     sync.RWMutex
+	filter        func(interface{}) bool
+	readOwn       atomic.Bool
+	stalledAt     int
+	id            uint64
+	withSender    atomic.Bool
+	renew         chan struct{}
+	name          string
+	bridge        *bridge
+	delivering    *Message
+	resolved      bool
+	shutdownOrder atomic.Int64
+
+	// In points to Read.
+	//
+	// Deprecated: In is kept for code written against the first
+	// upstream version of the package. Use Read instead.
+	In *chan interface{}
 }
 
 // Group provides a mechanism for the broadcast of messages to a
 // collection of channels.
 type Group struct {
-	in         chan Message
-	close      chan bool
-	members    []*Member
-	clock      int
-	memberLock sync.Mutex
-	clockLock  sync.Mutex
+	in           chan Message
+	close        chan bool
+	members      []*Member
+	clock        atomic.Int64
+	eos          bool
+	frozen       bool
+	held         []Message
+	nilPolicy    atomic.Int32
+	experiment   *fanoutExperiment
+	hibernate    time.Duration
+	limiters     []*Limiter
+	rate         atomic.Pointer[Limiter]
+	middleware   []Middleware
+	clockCheck   func(format string, args ...interface{})
+	panicPolicy  PanicPolicy
+	lastID       uint64
+	epoch        uint64
+	dist         Distribution
+	turn         int
+	audit        *auditor
+	names        map[string]*Member
+	authorize    Authorizer
+	queueCap     int
+	relays       int
+	shards       [][]*Member
+	shardEpoch   uint64
+	relaying     atomic.Int64
+	children     map[string]*Group
+	childStops   []func()
+	replayN      int
+	replayWindow time.Duration
+	replay       []retainedMessage
+	childLock    sync.Mutex
+	memberLock   sync.Mutex
 }
 
-// NewGroup creates a new broadcast group.
-func NewGroup() *Group {
+// NewGroup creates a new broadcast group configured by opts.
+func NewGroup(opts ...Option) *Group {
 	in := make(chan Message)
 	close := make(chan bool)
-	return &Group{in: in, close: close, clock: 0}
+	g := &Group{in: in, close: close}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
 }
 
 // MemberCount returns the number of members in the Broadcast Group.
@@ -67,7 +208,8 @@ func (g *Group) MemberCount() int {
 func (g *Group) Members() []*Member {
 	g.memberLock.Lock()
 	defer g.memberLock.Unlock()
-	res := g.members[:]
+	res := make([]*Member, len(g.members))
+	copy(res, g.members)
 	return res
 }
 
@@ -78,8 +220,96 @@ func (g *Group) Join() *Member {
 	return g.Add(memberChannel)
 }
 
+// JoinFiltered works like Join but the member only receives the
+// payloads satisfying filter. Errors and control values are not
+// filtered. The filter runs in the listener of the member before the
+// message is queued, so rejected payloads take no room in the queue.
+func (g *Group) JoinFiltered(filter func(interface{}) bool) *Member {
+	return g.add(make(chan interface{}), func(m *Member) { m.filter = filter })
+}
+
+// JoinBuffered works like Join but gives the member a Read channel
+// buffering n values, so that a member reading in bursts does not hold
+// back its queue while it is busy.
+func (g *Group) JoinBuffered(n int) *Member {
+	return g.Add(make(chan interface{}, n))
+}
+
+// JoinCtx works like Join but the returned member leaves the group by
+// itself as soon as ctx is done.
+func (g *Group) JoinCtx(ctx context.Context) *Member {
+	member := g.Join()
+	go func() {
+		select {
+		case <-ctx.Done():
+			g.leave(member, false)
+		case <-member.close:
+		}
+	}()
+	return member
+}
+
+// WaitFor joins the group, waits for a broadcast satisfying pred and
+// leaves. It returns the matching value or ctx.Err() when ctx is done
+// first. Only values sent after WaitFor was called are considered.
+func (g *Group) WaitFor(ctx context.Context, pred func(interface{}) bool) (interface{}, error) {
+	member := g.Join()
+	defer g.leave(member, false)
+	return member.RecvMatch(ctx, pred)
+}
+
+// WeakMember is a group member which leaves the group by itself once
+// the application drops every reference to it.
+type WeakMember struct {
+	*Member
+}
+
+// weakNotice is how long the close notice of a collected weak member
+// waits for a reader.
+const weakNotice = time.Second
+
+// JoinWeak works like Join but returns a member which is removed from
+// the group after the garbage collector finds the returned *WeakMember
+// unreferenced. It is a safety net for servers leaking subscribers,
+// not a replacement for Close: onLeak is called with the removed
+// member as a warning, or the leak is logged when onLeak is nil.
+//
+// Only the *WeakMember is watched, as the group itself references the
+// Read channel and the embedded *Member. Keep the *WeakMember for as
+// long as the member is used: code holding on to Read or Member alone
+// loses the membership at the next collection. Such a reader gets the
+// close notice, which waits weakNotice for it, instead of hanging.
+func (g *Group) JoinWeak(onLeak func(*Member)) *WeakMember {
+	weak := &WeakMember{Member: g.Join()}
+	runtime.SetFinalizer(weak, func(weak *WeakMember) {
+		member := weak.Member
+		if g.leave(member, false) != nil {
+			return // closed by the application
+		}
+		go func() {
+			select {
+			case member.Read <- Message{msg_type: MSG_TYPE_CLOSE}:
+			case <-time.After(weakNotice):
+			}
+		}()
+		if onLeak != nil {
+			g.guard("leak callback", func() { onLeak(member) })
+			return
+		}
+		log.Printf("bcast: member %p was not closed before being garbage collected", member)
+	})
+	return weak
+}
+
 // Leave removes the provided member from the group
 func (g *Group) Leave(leaving *Member) error {
+	return g.leave(leaving, true)
+}
+
+// leave removes the member from the group and stops its delivery. The
+// reader is told about it only on notify, as nobody may be left to
+// read the notice.
+func (g *Group) leave(leaving *Member, notify bool) error {
 	g.memberLock.Lock()
 	defer g.memberLock.Unlock()
 	memberIndex := -1
@@ -93,42 +323,89 @@ func (g *Group) Leave(leaving *Member) error {
 		return errors.New("Could not find provided memeber for removal")
 	}
 	g.members = append(g.members[:memberIndex], g.members[memberIndex+1:]...)
-	go func() {
-		leaving.Read <- Message{msg_type: MSG_TYPE_CLOSE, sender: nil, payload: nil}
-	}()
-	leaving.close <- true // TODO: need to handle the case where there
+	g.epoch++
+	if leaving.name != "" {
+		delete(g.names, leaving.name)
+	}
+	if notify {
+		go func() {
+			leaving.Read <- Message{msg_type: MSG_TYPE_CLOSE, sender: nil, payload: nil}
+		}()
+	}
+	close(leaving.close) // TODO: need to handle the case where there
 	// is still stuff in this Members priorityQueue
 	return nil
 }
 
 // Add adds a member to the group for the provided interface channel.
 func (g *Group) Add(memberChannel chan interface{}) *Member {
+	return g.add(memberChannel, nil)
+}
+
+// add adds a member reading from memberChannel to the group. configure,
+// if any, sets the member up before its listener starts.
+func (g *Group) add(memberChannel chan interface{}, configure func(*Member)) *Member {
 	g.memberLock.Lock()
 	defer g.memberLock.Unlock()
+	return g.addLocked(memberChannel, configure)
+}
 
-	g.clockLock.Lock()
-	defer g.clockLock.Unlock()
+// addLocked works like add for callers holding memberLock.
+func (g *Group) addLocked(memberChannel chan interface{}, configure func(*Member)) *Member {
 	member := &Member{
 		group:        g,
 		Read:         memberChannel,
-		clock:        g.clock,
-		messageQueue: PriorityQueue{},
+		clock:        int(g.clock.Load()),
+		messageQueue: make(PriorityQueue, 0, g.queueCap),
+		queueCap:     g.queueCap,
 		send:         make(chan Message),
+		resync:       make(chan bool, 1),
 		close:        make(chan bool),
 	}
+	member.In = &member.Read
+	if configure != nil {
+		configure(member)
+	}
+	g.lastID++
+	member.id = g.lastID
+	if g.replays() {
+		g.replayTo(member)
+	}
+	member.listening = true
 	go member.listen()
 	g.members = append(g.members, member)
+	g.epoch++
 	return member
 }
 
-// Close terminates the group immediately.
+// Close terminates the group immediately, along with its children.
 func (g *Group) Close() {
 	g.close <- true
+	g.closeChildren()
 }
 
 // Broadcast messages received from one group member to others.
 // If incoming messages not arrived during `timeout` then function returns.
 func (g *Group) Broadcast(timeout time.Duration) {
+	g.broadcast(timeout, nil)
+}
+
+// Run broadcasts messages like Broadcast until ctx is done or the group
+// is closed. It returns ctx.Err() in the first case and nil in the
+// second, which fits errgroup.Group.Go and the actor functions of
+// oklog/run:
+//
+//	eg.Go(func() error { return group.Run(ctx) })
+func (g *Group) Run(ctx context.Context) error {
+	if g.broadcast(0, ctx.Done()) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// broadcast runs the broadcast loop and reports whether it was stopped
+// by done.
+func (g *Group) broadcast(timeout time.Duration, done <-chan struct{}) bool {
 	var timeoutChannel <-chan time.Time
 	if timeout != 0 {
 		timeoutChannel = time.After(timeout)
@@ -136,37 +413,335 @@ func (g *Group) Broadcast(timeout time.Duration) {
 	for {
 		select {
 		case received := <-g.in:
-			g.memberLock.Lock()
+			// Take everything that is already waiting so a burst
+			// is stamped and fanned out under a single lock.
+			batch := []Message{received}
+		drain:
+			for len(batch) < maxBatch {
+				select {
+				case next := <-g.in:
+					batch = append(batch, next)
+				default:
+					break drain
+				}
+			}
+			g.fanOut(g.intercept(batch))
+		case <-timeoutChannel:
+			if timeout > 0 {
+				return false
+			}
+		case <-g.close:
+			return false
+		case <-done:
+			return true
+		}
+	}
+}
+
+// fanOut stamps the batch with clocks and hands it over to every
+// member.
+func (g *Group) fanOut(batch []Message) {
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	if g.frozen {
+		g.held = append(g.held, batch...)
+		return
+	}
+	g.fanOutLocked(batch)
+}
 
-			members := g.members[:]
+// fanOutLocked is fanOut for callers holding the member lock.
+func (g *Group) fanOutLocked(batch []Message) {
+	stamped := batch[:0]
+	for _, message := range batch {
+		if g.eos {
+			// Nothing is broadcast after the end of stream.
+			message.report.expect(0)
+			continue
+		}
+		if message.msg_type == MSG_TYPE_EOS {
+			g.eos = true
+		}
+		stamped = append(stamped, message)
+	}
+	if len(stamped) == 0 {
+		return
+	}
+	// Reserve the clocks of the whole batch with a single atomic add.
+	clock := int(g.clock.Add(int64(len(stamped)))) - len(stamped)
+	for i := range stamped {
+		if stamped[i].dist != Broadcast && stamped[i].to == nil {
+			stamped[i].to = g.pick(&stamped[i])
+			stamped[i].filtered = true
+		}
+		stamped[i].clock = clock + i
+		if env, ok := stamped[i].payload.(*Envelope); ok && stamped[i].msg_type == MSG_TYPE_DATA {
+			env.Seq = clock + i
+		}
+	}
+	if g.replays() {
+		g.retain(stamped)
+	}
+	for _, message := range stamped {
+		message.report.expect(len(g.members))
+		if g.audit != nil && !message.replayed {
+			if g.guard("audit", func() { g.audit.sent(&message) }) {
+				g.isolate(message.sender)
+			}
+		}
+	}
+
+	// Small groups and relays first try to hand the batch over
+	// directly, which saves spawning a goroutine per member when
+	// listeners are idle.
+	direct := g.hibernate == 0 && g.experiment == nil
+	if g.relays == 0 || len(g.members) <= g.relays {
+		for _, member := range g.members {
+			member.backlog.Add(int64(len(stamped)))
+		}
+		g.handOver(g.members, stamped, direct && len(g.members) <= smallGroup)
+		return
+	}
+	// Wide groups are split between relays, which also account the
+	// batch to their members, so the loop only does work per relay.
+	for _, shard := range g.relayShards() {
+		g.relaying.Add(1)
+		go g.relay(shard, stamped, direct)
+	}
+}
+
+// relayShards returns the members split between the relays. The shards
+// are kept until the members change, and are never modified once
+// built since relays of earlier batches may still be reading them.
+func (g *Group) relayShards() [][]*Member {
+	if g.shards != nil && g.shardEpoch == g.epoch {
+		return g.shards
+	}
+	members := make([]*Member, len(g.members))
+	copy(members, g.members)
+	size := (len(members) + g.relays - 1) / g.relays
+	g.shards = g.shards[:0:0]
+	for len(members) > 0 {
+		n := min(size, len(members))
+		g.shards = append(g.shards, members[:n:n])
+		members = members[n:]
+	}
+	g.shardEpoch = g.epoch
+	return g.shards
+}
 
-			g.clockLock.Lock()
-			received.clock = g.clock
-			g.clock++
-			g.clockLock.Unlock()
+// relay accounts the stamped messages to a shard of members and hands
+// them over.
+func (g *Group) relay(members []*Member, stamped []Message, direct bool) {
+	for _, member := range members {
+		member.backlog.Add(int64(len(stamped)))
+	}
+	g.relaying.Add(-1)
+	g.handOver(members, stamped, direct)
+}
 
-			for _, member := range members {
-				// This is done in a goroutine because if it
-				// weren't it would be a blocking call
-				go func(member *Member, received Message) {
-					member.send <- received
-				}(member, received)
+// handOver delivers the stamped messages to the members. With direct
+// set it tries to pass them to the listeners at once and only starts
+// delivery goroutines for the rest.
+func (g *Group) handOver(members []*Member, stamped []Message, direct bool) {
+	for _, member := range members {
+		pending := stamped
+		if direct {
+			pending = stamped[member.tryReceive(stamped):]
+			if len(pending) == 0 {
+				continue
 			}
+		}
+		// This is done in a goroutine because if it
+		// weren't it would be a blocking call
+		go g.deliver(member, pending)
+	}
+}
 
-			g.memberLock.Unlock()
-		case <-timeoutChannel:
-			if timeout > 0 {
-				return
+// deliver hands the stamped messages over to the member.
+func (g *Group) deliver(member *Member, stamped []Message) {
+	if g.hibernate > 0 {
+		member.wake()
+		defer member.rest()
+	}
+	if e := g.experiment; e != nil {
+		e.run(func() { member.receive(stamped) })
+		return
+	}
+	member.receive(stamped)
+}
+
+// tryReceive passes the messages to the listener of the member as
+// long as it is ready to take them and returns how many it took.
+func (m *Member) tryReceive(messages []Message) int {
+	for i, message := range messages {
+		select {
+		case m.send <- message:
+		default:
+			return i
+		}
+	}
+	return len(messages)
+}
+
+// receive passes the messages to the listener of the member, unless
+// the member leaves meanwhile.
+func (m *Member) receive(messages []Message) {
+	for i, message := range messages {
+		select {
+		case m.send <- message:
+		case <-m.close:
+			for _, lost := range messages[i:] {
+				m.resolve(&lost, false)
 			}
-		case <-g.close:
 			return
 		}
 	}
 }
 
+// Freeze halts the fan-out of the group. Messages sent meanwhile are
+// accepted but held back until Unfreeze. Freeze does not wait for the
+// messages fanned out before, which may still reach the members after
+// it returns; once Backlog dropped to zero the state of every consumer
+// stays put, e.g. while a consistent snapshot is taken. Freeze does not
+// wait itself since a member which stops reading would hold it forever.
+func (g *Group) Freeze() {
+	g.freeze()
+}
+
+// freeze works like Freeze and reports whether the group was not
+// frozen already.
+func (g *Group) freeze() bool {
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	frozen := g.frozen
+	g.frozen = true
+	return !frozen
+}
+
+// Unfreeze resumes the fan-out halted by Freeze, broadcasting the held
+// back messages first.
+func (g *Group) Unfreeze() {
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	if !g.frozen {
+		return
+	}
+	g.frozen = false
+	held := g.held
+	g.held = nil
+	if len(held) > 0 {
+		g.fanOutLocked(held)
+	}
+}
+
 // Send broadcasts a message to every one of a Group's members.
 func (g *Group) Send(val interface{}) {
-	g.in <- Message{msg_type: MSG_TYPE_DATA, sender: nil, payload: val}
+	g.in <- g.data(nil, val)
+}
+
+// TrySend broadcasts a message like Send if the broadcast loop takes it
+// at once. It returns false without blocking when the loop is busy or
+// not running, or when the nil policy rejects val.
+func (g *Group) TrySend(val interface{}) bool {
+	if g.rejects(val) {
+		return false
+	}
+	return g.offer(g.message(nil, val))
+}
+
+// offer hands message over to the broadcast loop if both the loop and
+// the limiters of the group take it at once. Tokens taken for a message
+// the loop refuses are given back.
+func (g *Group) offer(message Message) bool {
+	taken, err := g.reserve(context.Background(), message.payload, false)
+	if err != nil {
+		return false
+	}
+	select {
+	case g.in <- message:
+		return true
+	default:
+		taken.refund()
+		return false
+	}
+}
+
+// SendContext broadcasts a message like Send but gives up when ctx is
+// done before the broadcast loop takes the message, for example
+// because the loop has stopped. It returns ctx.Err() in that case, and
+// ErrNilPayload when the nil policy rejects val.
+func (g *Group) SendContext(ctx context.Context, val interface{}) error {
+	if g.rejects(val) {
+		return ErrNilPayload
+	}
+	message := g.message(nil, val)
+	taken, err := g.reserve(ctx, val, true)
+	if err != nil {
+		return err
+	}
+	select {
+	case g.in <- message:
+		return nil
+	case <-ctx.Done():
+		taken.refund()
+		return ctx.Err()
+	}
+}
+
+// data packs a payload sent by sender like message, after waiting for
+// the limiters of the group.
+func (g *Group) data(sender *Member, val interface{}) Message {
+	message := g.message(sender, val)
+	g.reserve(context.Background(), val, true)
+	return message
+}
+
+// message packs a payload sent by sender, enforcing the nil policy.
+func (g *Group) message(sender *Member, val interface{}) Message {
+	if g.rejects(val) {
+		panic(ErrNilPayload)
+	}
+	return Message{msg_type: MSG_TYPE_DATA, sender: sender, payload: val, dist: g.dist}
+}
+
+// rejects reports whether the nil policy of the group rejects val.
+func (g *Group) rejects(val interface{}) bool {
+	return val == nil && NilPolicy(g.nilPolicy.Load()) == NilReject
+}
+
+// SendDroppable broadcasts a message which may be lost instead of
+// holding anybody back: it is discarded when the broadcast loop is
+// busy and is skipped by every member not ready to read it at once.
+// It suits high-frequency updates where only fresh values matter.
+func (g *Group) SendDroppable(val interface{}) {
+	g.offer(g.droppable(nil, val))
+}
+
+// droppable packs a payload for the at-most-once lane. It is not
+// limited yet, see offer.
+func (g *Group) droppable(sender *Member, val interface{}) Message {
+	message := g.message(sender, val)
+	message.droppable = true
+	return message
+}
+
+// SendError broadcasts err to every one of a Group's members. Members
+// receive it as *ErrorPayload, distinct from regular payloads. A nil
+// err is not sent.
+func (g *Group) SendError(err error) {
+	if err == nil {
+		return
+	}
+	g.in <- Message{msg_type: MSG_TYPE_ERROR, sender: nil, payload: err}
+}
+
+// CloseSend broadcasts EOS to every one of a Group's members and
+// discards everything sent to the group afterwards. Unlike Close it
+// does not stop the broadcast loop, so members still receive all the
+// values sent before.
+func (g *Group) CloseSend() {
+	g.in <- Message{msg_type: MSG_TYPE_EOS, sender: nil, payload: nil}
 }
 
 // Close removes the member it is called on from its broadcast group.
@@ -177,56 +752,407 @@ func (m *Member) Close() {
 // Send broadcasts a message from one Member to the channels of all
 // the other members in its group.
 func (m *Member) Send(val interface{}) {
-	m.group.in <- Message{msg_type: MSG_TYPE_DATA, sender: m, payload: val}
+	message := m.group.data(m, val)
+	if m.readOwn.Load() {
+		m.stashLock.Lock()
+		m.stash = append(m.stash, val)
+		m.stashLock.Unlock()
+	}
+	m.group.in <- message
+}
+
+// ReadYourWrites makes the values the member sends with Send visible to
+// its own Recv, TryRecv, RecvN, RecvTimeout, RecvContext and RecvMatch
+// calls before Send returns, while the others get them asynchronously.
+// Values read directly from the Read channel don't include them.
+func (m *Member) ReadYourWrites(on bool) {
+	m.readOwn.Store(on)
+}
+
+// SendDroppable broadcasts a message from one Member to the other
+// members of its group with the at-most-once semantics of
+// Group.SendDroppable.
+func (m *Member) SendDroppable(val interface{}) {
+	m.group.offer(m.group.droppable(m, val))
+}
+
+// SendError broadcasts err from one Member to all the other members in
+// its group. They receive it as *ErrorPayload. A nil err is not sent.
+func (m *Member) SendError(err error) {
+	if err == nil {
+		return
+	}
+	m.group.in <- Message{msg_type: MSG_TYPE_ERROR, sender: m, payload: err}
+}
+
+// SetTier assigns the member to a tier. Best-effort members drop
+// messages instead of holding them back when they fall behind, while
+// critical members keep full delivery guarantees.
+func (m *Member) SetTier(tier Tier) {
+	m.tier.Store(int32(tier))
+}
+
+// Resync asks the member to resynchronize its clock with the group
+// when it got stuck waiting for a message that will never arrive. The
+// member skips to the oldest message it holds back or, with nothing
+// held back, to the current clock of the group. Messages being in
+// flight at that moment may be lost. Resync does not block.
+func (m *Member) Resync() {
+	select {
+	case m.resync <- true:
+	default:
+	}
 }
 
 // Recv reads one value from the member's Read channel
 func (m *Member) Recv() interface{} {
+	if val, ok := m.unstash(); ok {
+		return val
+	}
 	return <-m.Read
 }
 
+// TryRecv reads one value if it is available at once. It reports
+// false without blocking otherwise.
+func (m *Member) TryRecv() (interface{}, bool) {
+	if val, ok := m.unstash(); ok {
+		return val, true
+	}
+	select {
+	case val := <-m.Read:
+		return val, true
+	default:
+		return nil, false
+	}
+}
+
+// RecvTimeout reads one value like Recv but waits for it at most d. It
+// returns ErrTimeout when the time is up.
+func (m *Member) RecvTimeout(d time.Duration) (interface{}, error) {
+	if val, ok := m.unstash(); ok {
+		return val, nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case val := <-m.Read:
+		return val, nil
+	case <-timer.C:
+		return nil, ErrTimeout
+	}
+}
+
+// RecvContext reads one value like Recv but returns ctx.Err() when
+// ctx is done before a value arrives.
+func (m *Member) RecvContext(ctx context.Context) (interface{}, error) {
+	if val, ok := m.unstash(); ok {
+		return val, nil
+	}
+	select {
+	case val := <-m.Read:
+		return val, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// RecvN reads up to n values from the member's Read channel, waiting
+// for them at most max. It returns what was read when the time is up.
+func (m *Member) RecvN(n int, max time.Duration) []interface{} {
+	vals := make([]interface{}, 0, n)
+	deadline := time.NewTimer(max)
+	defer deadline.Stop()
+	for len(vals) < n {
+		if val, ok := m.unstash(); ok {
+			vals = append(vals, val)
+			continue
+		}
+		select {
+		case val := <-m.Read:
+			vals = append(vals, val)
+		case <-deadline.C:
+			return vals
+		}
+	}
+	return vals
+}
+
+// MatchMode tells RecvMatch what to do with the values it skips.
+type MatchMode int
+
+const (
+	// MatchDiscard drops skipped values. It is the default.
+	MatchDiscard MatchMode = iota
+	// MatchRequeue keeps skipped values for the next Recv, RecvN or
+	// RecvMatch calls, which return them first and in order.
+	MatchRequeue
+)
+
+// RecvMatch reads values until one satisfies pred and returns it. The
+// values skipped on the way are dropped unless MatchRequeue is passed.
+// When ctx is done first, RecvMatch returns ctx.Err().
+func (m *Member) RecvMatch(ctx context.Context, pred func(interface{}) bool, mode ...MatchMode) (interface{}, error) {
+	requeue := len(mode) > 0 && mode[0] == MatchRequeue
+	m.stashLock.Lock()
+	for i, val := range m.stash {
+		if pred(val) {
+			m.stash = append(m.stash[:i], m.stash[i+1:]...)
+			m.stashLock.Unlock()
+			return val, nil
+		}
+	}
+	m.stashLock.Unlock()
+	for {
+		select {
+		case val := <-m.Read:
+			if pred(val) {
+				return val, nil
+			}
+			if requeue {
+				m.stashLock.Lock()
+				m.stash = append(m.stash, val)
+				m.stashLock.Unlock()
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// unstash takes the oldest value put aside by RecvMatch or by Send
+// for read-your-writes.
+func (m *Member) unstash() (interface{}, bool) {
+	m.stashLock.Lock()
+	defer m.stashLock.Unlock()
+	if len(m.stash) == 0 {
+		return nil, false
+	}
+	val := m.stash[0]
+	m.stash = m.stash[1:]
+	return val, true
+}
+
 func (m *Member) listen() {
+	var (
+		timer *time.Timer
+		idle  <-chan time.Time
+	)
+	if m.group.hibernate > 0 {
+		timer = time.NewTimer(m.group.hibernate)
+		defer timer.Stop()
+		idle = timer.C
+	}
 	for {
 		select {
 		case message := <-m.send:
-			m.handleMessage(&message)
+			m.safely(func() { m.handleMessage(&message) })
+		case <-m.resync:
+			m.safely(m.resynchronize)
+		case <-idle:
+			if m.park() {
+				return
+			}
 		case <-m.close:
+			m.drop()
 			return
 		}
+		if timer != nil {
+			timer.Reset(m.group.hibernate)
+		}
+	}
+}
+
+// drop discards the messages held back by a leaving member.
+func (m *Member) drop() {
+	for m.messageQueue.Len() > 0 {
+		item := heap.Pop(&m.messageQueue).(*Item)
+		m.resolve(item.value.(*Message), false)
+		releaseItem(item)
+	}
+}
+
+// wake makes sure a hibernating member listens before messages are
+// handed over to it.
+func (m *Member) wake() {
+	m.listenLock.Lock()
+	defer m.listenLock.Unlock()
+	m.inbound++
+	if !m.listening {
+		m.listening = true
+		go m.listen()
 	}
 }
 
+// rest tells the member a hand-over started by wake is finished.
+func (m *Member) rest() {
+	m.listenLock.Lock()
+	defer m.listenLock.Unlock()
+	m.inbound--
+}
+
+// park stops the listener of an idle member. A member with messages
+// being handed over or held back in its queue is not idle.
+func (m *Member) park() bool {
+	m.listenLock.Lock()
+	defer m.listenLock.Unlock()
+	if m.inbound > 0 || m.messageQueue.Len() > 0 {
+		return false
+	}
+	m.messageQueue = nil // release the backing array
+	m.listening = false
+	return true
+}
+
 func (m *Member) handleMessage(message *Message) {
+	if before(message.clock, m.clock) {
+		// Stale message left behind by a resync.
+		m.backlog.Add(-1)
+		m.resolve(message, false)
+		return
+	}
+	if message.sender != m && !m.wants(message) {
+		// Keep a placeholder without the payload so the clock of
+		// the member still advances past the message; it is
+		// skipped like the member's own messages.
+		message.payload = nil
+		message.sender = m
+	}
 	if !m.trySend(message) {
-		heap.Push(&m.messageQueue, &Item{
-			priority: message.clock,
-			value:    message,
-		})
+		heap.Push(&m.messageQueue, newItem(message, message.clock))
+		if m.group.clockCheck != nil {
+			m.checkClock(message.clock)
+		}
+		return
+	}
+	m.sendQueued()
+}
+
+// checkClock reports the member when the gap between its clock and the
+// clock of a message it has to hold back is wider than everything
+// still undelivered to it, so the missing messages can't arrive.
+func (m *Member) checkClock(received int) {
+	gap := received - m.clock
+	backlog := m.backlog.Load()
+	if int64(gap) < backlog || m.stalledAt == m.clock+1 {
 		return
 	}
+	m.stalledAt = m.clock + 1 // zero means no stall was reported
+	if m.group.guard("clock check", func() {
+		m.group.clockCheck("bcast: member expects clock %d but received %d with %d messages undelivered and %d queued",
+			m.clock, received, backlog, m.messageQueue.Len())
+	}) {
+		m.group.isolate(m)
+	}
+}
+
+// wants reports whether the member receives message according to its
+// recipient, its subscriptions, its tags and its filter.
+func (m *Member) wants(message *Message) bool {
+	if message.to != nil && message.to != m || !m.subscribed(message.topic) || !m.tagged(message.tag) {
+		return false
+	}
+	// The filter of the recipient picked for a distributed message
+	// accepted it already.
+	return message.filtered || m.accepts(message)
+}
+
+// accepts reports whether the filter of the member, if any, lets the
+// message through. Only data messages are filtered.
+func (m *Member) accepts(message *Message) bool {
+	if m.filter == nil || message.msg_type != MSG_TYPE_DATA {
+		return true
+	}
+	var accepted bool
+	if m.group.guard("member filter", func() { accepted = m.filter(message.payload) }) {
+		m.group.isolate(m)
+	}
+	return accepted
+}
+
+func (m *Member) resynchronize() {
+	if m.messageQueue.Len() > 0 {
+		m.clock = m.messageQueue[0].priority
+	} else {
+		m.clock = int(m.group.clock.Load())
+	}
+	m.sendQueued()
+}
+
+func (m *Member) sendQueued() {
 	if m.messageQueue.Len() > 0 {
 		nextMessage := m.messageQueue[0].value.(*Message)
 		for m.trySend(nextMessage) {
-			heap.Pop(&m.messageQueue)
+			releaseItem(heap.Pop(&m.messageQueue).(*Item))
 			if m.messageQueue.Len() > 0 {
 				nextMessage = m.messageQueue[0].value.(*Message)
 			} else {
 				break
 			}
 		}
+		m.messageQueue.shrink(m.queueCap)
+	}
+}
+
+// resolve records the outcome of the delivery of message to the member.
+func (m *Member) resolve(message *Message, delivered bool) {
+	message.report.resolve(delivered)
+	if m.group.audit != nil {
+		m.group.audit.delivery(m, message, delivered)
 	}
 }
 
 func (m *Member) trySend(message *Message) bool {
 	shouldSend := message.clock == m.clock
 	if shouldSend {
+		defer m.backlog.Add(-1)
+		m.delivering, m.resolved = message, false
 		if message.sender != m {
-			if message.msg_type == MSG_TYPE_DATA {
-				m.Read <- message.payload
+			var val interface{}
+			switch message.msg_type {
+			case MSG_TYPE_DATA:
+				val = message.payload
+				if m.bridge != nil {
+					val = forwarded{payload: message.payload, topic: message.topic, hops: message.hops}
+					break
+				}
+				if _, ok := val.(*Envelope); !ok && NilPolicy(m.group.nilPolicy.Load()) == NilWrap {
+					val = &Envelope{Payload: val, Seq: message.clock}
+				}
+				if m.withSender.Load() {
+					val = &From{Sender: message.sender, Payload: val}
+				}
+			case MSG_TYPE_ERROR:
+				err, _ := message.payload.(error)
+				val = &ErrorPayload{Err: err}
+			case MSG_TYPE_EOS:
+				val = EOS
+			case MSG_TYPE_CONFIG:
+				val = message.payload
+			}
+			bestEffort := Tier(m.tier.Load()) == TierBestEffort
+			shed := bestEffort && (message.shed || m.backlog.Load() > bestEffortBacklog)
+			delivered := false
+			if message.droppable || shed {
+				select {
+				case m.Read <- val:
+					delivered = true
+				default:
+				}
 			} else {
-				m.Read <- nil
+				// A member leaving the group must not stay
+				// blocked on a reader which is already gone.
+				select {
+				case m.Read <- val:
+					delivered = true
+				case <-m.close:
+				}
 			}
+			m.resolved = true
+			m.resolve(message, delivered)
+		} else {
+			message.report.skip()
 		}
+		m.delivering = nil
 		m.clock++
 	}
 	return shouldSend
@@ -10,7 +10,13 @@ package bcast
 */
 
 import (
+	"container/heap"
+	"context"
+	"errors"
 	"gopkg.in/fatih/set.v0"
+	"math"
+	"os"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -66,13 +72,13 @@ func TestMemberBroadcast(t *testing.T) {
 			} else {
 				val := m.Recv()
 				if val != broadcaster {
-					t.Fatal("incorrect message received")
+					t.Error("incorrect message received")
 				}
 			}
 			channel <- true
 			val := m.Recv()
 			if val != "done" {
-				t.Fatal("incorrect message received")
+				t.Error("incorrect message received")
 			}
 			channel <- true
 		}(i, group, c, m)
@@ -106,7 +112,7 @@ func TestGroupBroadcast(t *testing.T) {
 		go func(i int, group *Group, channel chan bool, member *Member) {
 			val := m.Recv()
 			if val != "group message" {
-				t.Fatal("incorrect message received")
+				t.Error("incorrect message received")
 			}
 			channel <- true
 		}(i, group, c, m)
@@ -144,7 +150,7 @@ func TestBroadcastOnLargeNumberOfMembers(t *testing.T) {
 			for {
 				newValue := m.Recv()
 				if encountered.Has(newValue) {
-					t.Fatal("Received duplicate value")
+					t.Error("Received duplicate value")
 				}
 				encountered.Add(newValue)
 				if encountered.IsEqual(expected) {
@@ -159,3 +165,567 @@ func TestBroadcastOnLargeNumberOfMembers(t *testing.T) {
 		<-channel
 	}
 }
+
+// Create new broadcast group.
+// Send an error in-band and unwrap it on the receiving side.
+func TestSendError(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go group.SendError(os.ErrNotExist)
+	err, ok := member.Recv().(error)
+	if !ok {
+		t.Fatal("error payload must implement error")
+	}
+	var payload *ErrorPayload
+	if !errors.As(err, &payload) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("error payload does not unwrap to the sent error")
+	}
+
+	// A nil error is not sent at all.
+	go func() {
+		group.SendError(nil)
+		member.SendError(nil)
+		group.Send("after nil")
+	}()
+	if val := member.Recv(); val != "after nil" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
+
+// Create new broadcast group.
+// Close sending and check that EOS is the last value received.
+func TestCloseSend(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go func() {
+		group.Send("last message")
+		group.CloseSend()
+		group.Send("discarded message")
+	}()
+	if val := member.Recv(); val != "last message" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	if val := member.Recv(); val != EOS {
+		t.Fatalf("expected EOS, got %v", val)
+	}
+	select {
+	case val := <-member.Read:
+		t.Fatalf("value %v received after EOS", val)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Create new broadcast group.
+// Join a member bound to a context and cancel it.
+func TestJoinCtx(t *testing.T) {
+	group := NewGroup()
+	ctx, cancel := context.WithCancel(context.Background())
+	group.JoinCtx(ctx)
+	go group.Broadcast(0)
+
+	group.Send("unread message")
+	cancel()
+	for i := 0; group.MemberCount() != 0; i++ {
+		if i == 100 {
+			t.Fatal("member must leave when its context is done")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Create new broadcast group.
+// Join a weak member and drop the reference to it.
+func TestJoinWeak(t *testing.T) {
+	group := NewGroup()
+	leaked := make(chan *Member, 1)
+	group.JoinWeak(func(m *Member) { leaked <- m })
+
+	for i := 0; group.MemberCount() != 0; i++ {
+		if i == 100 {
+			t.Fatal("unreferenced weak member must leave the group")
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-leaked:
+	case <-time.After(time.Second):
+		t.Fatal("leak must be reported")
+	}
+}
+
+// Create new broadcast group.
+// Join a weak member keeping only its Read channel.
+// Check that the reader is told when the member is collected.
+func TestJoinWeakRead(t *testing.T) {
+	group := NewGroup()
+	read := group.JoinWeak(func(*Member) {}).Read
+	go group.Broadcast(0)
+
+	for i := 0; group.MemberCount() != 0; i++ {
+		if i == 100 {
+			t.Fatal("unreferenced weak member must leave the group")
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case val := <-read:
+		if _, ok := val.(Message); !ok {
+			t.Fatalf("expected close notice, got %v", val)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reader of a collected member must not hang")
+	}
+}
+
+// Create new broadcast group.
+// Send a burst of messages and check they arrive in order.
+func TestBurstOrder(t *testing.T) {
+	const max = 1000
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go func() {
+		for i := 0; i < max; i++ {
+			group.Send(i)
+		}
+	}()
+	for i := 0; i < max; i++ {
+		if val := member.Recv(); val != i {
+			t.Fatalf("expected %d, got %v", i, val)
+		}
+	}
+}
+
+// Fill a priority queue with a burst and empty it again.
+// Check that the backing array shrinks afterwards.
+func TestQueueShrink(t *testing.T) {
+	pq := make(PriorityQueue, 0, 4)
+	for i := 0; i < 1024; i++ {
+		heap.Push(&pq, newItem(nil, i))
+	}
+	for pq.Len() > 8 {
+		releaseItem(heap.Pop(&pq).(*Item))
+		pq.shrink(0)
+	}
+	if cap(pq) > 4*shrinkCapacity {
+		t.Fatalf("capacity %d was not given back", cap(pq))
+	}
+	for expected := 1016; pq.Len() > 0; expected++ {
+		if item := heap.Pop(&pq).(*Item); item.priority != expected {
+			t.Fatalf("expected priority %d, got %d", expected, item.priority)
+		}
+	}
+
+	group := NewGroup(WithQueueCapacity(16))
+	if member := group.Join(); cap(member.messageQueue) != 16 {
+		t.Fatalf("queue capacity %d, want 16", cap(member.messageQueue))
+	}
+}
+
+// Fill a priority queue created with a large capacity and empty it.
+// Check that the backing array never shrinks below that capacity.
+func TestQueueShrinkFloor(t *testing.T) {
+	pq := make(PriorityQueue, 0, 512)
+	for i := 0; i < 4096; i++ {
+		heap.Push(&pq, newItem(nil, i))
+	}
+	for pq.Len() > 0 {
+		releaseItem(heap.Pop(&pq).(*Item))
+		pq.shrink(512)
+	}
+	if cap(pq) != 512 {
+		t.Fatalf("capacity %d instead of 512", cap(pq))
+	}
+}
+
+// Create new broadcast group with the clock near its maximum.
+// Send messages across the wraparound boundary.
+func TestClockWraparound(t *testing.T) {
+	pq := PriorityQueue{}
+	for _, priority := range []int{math.MinInt, math.MaxInt, math.MinInt + 1, math.MaxInt - 1} {
+		heap.Push(&pq, &Item{priority: priority})
+	}
+	for _, expected := range []int{math.MaxInt - 1, math.MaxInt, math.MinInt, math.MinInt + 1} {
+		if item := heap.Pop(&pq).(*Item); item.priority != expected {
+			t.Fatalf("expected priority %d, got %d", expected, item.priority)
+		}
+	}
+
+	group := NewGroup()
+	group.clock.Store(math.MaxInt - 2)
+	member := group.Join()
+	go group.Broadcast(0)
+	go func() {
+		for i := 0; i < 5; i++ {
+			group.Send(i)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if val := member.Recv(); val != i {
+			t.Fatalf("expected %d, got %v", i, val)
+		}
+	}
+}
+
+// Create new broadcast group.
+// Make a member wait for a lost message and resync it.
+func TestResync(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	group.clock.Store(5) // the messages with clocks 0-4 are lost
+	go group.Broadcast(0)
+
+	go group.Send("after gap")
+	select {
+	case val := <-member.Read:
+		t.Fatalf("value %v delivered across the gap", val)
+	case <-time.After(50 * time.Millisecond):
+	}
+	member.Resync()
+	if val := member.Recv(); val != "after gap" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
+
+// Create new broadcast group with a busy member, whose Read is full,
+// and an idle one. Check that a droppable message skips the busy
+// member only.
+func TestSendDroppable(t *testing.T) {
+	group := NewGroup()
+	busyRead, idleRead := make(chan interface{}, 1), make(chan interface{}, 1)
+	busy, idle := group.Add(busyRead), group.Add(idleRead)
+	go group.Broadcast(0)
+
+	group.SendTo(busy, "filler")
+	for len(busyRead) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The loop itself may drop the message while busy, so offer it
+	// until the loop takes it.
+	for !group.offer(group.droppable(nil, "fresh")) {
+		time.Sleep(time.Millisecond)
+	}
+	go group.Send("reliable")
+	for _, expected := range []string{"fresh", "reliable"} {
+		if val, err := idle.RecvTimeout(time.Second); val != expected {
+			t.Fatalf("idle member expected %v, got %v (%v)", expected, val, err)
+		}
+	}
+	// Wait until the busy member skipped the droppable message and
+	// only holds the reliable one back.
+	for busy.backlog.Load() > 1 {
+		time.Sleep(time.Millisecond)
+	}
+	for _, expected := range []string{"filler", "reliable"} {
+		if val, err := busy.RecvTimeout(time.Second); val != expected {
+			t.Fatalf("busy member expected %v, got %v (%v)", expected, val, err)
+		}
+	}
+}
+
+// Create new broadcast group.
+// Join a critical and a best-effort member and overload them.
+func TestTiers(t *testing.T) {
+	const max = 200
+	group := NewGroup()
+	critical := group.Join()
+	bestEffort := group.Join()
+	bestEffort.SetTier(TierBestEffort)
+	go group.Broadcast(0)
+
+	for i := 0; i < max; i++ {
+		group.Send(i)
+	}
+	for i := 0; i < max; i++ {
+		if val := critical.Recv(); val != i {
+			t.Fatalf("critical member expected %d, got %v", i, val)
+		}
+	}
+	received := 0
+	for {
+		select {
+		case <-bestEffort.Read:
+			received++
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	if received >= max {
+		t.Fatal("best-effort member must shed under backpressure")
+	}
+}
+
+// Create new broadcast group.
+// Run it until its context is cancelled.
+func TestRun(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- group.Run(ctx) }()
+
+	go group.Send("run message")
+	if val := member.Recv(); val != "run message" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+// Create new broadcast group.
+// Receive messages in chunks limited by count and by time.
+func TestRecvN(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			group.Send(i)
+		}
+	}()
+	if vals := member.RecvN(2, time.Second); len(vals) != 2 || vals[0] != 0 || vals[1] != 1 {
+		t.Fatalf("unexpected chunk %v", vals)
+	}
+	if vals := member.RecvN(2, 50*time.Millisecond); len(vals) != 1 || vals[0] != 2 {
+		t.Fatalf("unexpected partial chunk %v", vals)
+	}
+}
+
+// Create new broadcast group.
+// Wait for a matching message and requeue the others.
+func TestRecvMatch(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			group.Send(i)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	isTwo := func(val interface{}) bool { return val == 2 }
+	if val, err := member.RecvMatch(ctx, isTwo, MatchRequeue); err != nil || val != 2 {
+		t.Fatalf("unexpected match %v, %v", val, err)
+	}
+	if vals := member.RecvN(2, time.Second); len(vals) != 2 || vals[0] != 0 || vals[1] != 1 {
+		t.Fatalf("skipped values must be requeued, got %v", vals)
+	}
+	if _, err := member.RecvMatch(ctx, isTwo); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+}
+
+// Create new broadcast group.
+// Block until the awaited message is broadcast.
+func TestWaitFor(t *testing.T) {
+	group := NewGroup()
+	go group.Broadcast(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		for group.MemberCount() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		group.Send("other")
+		group.Send("ready")
+	}()
+	val, err := group.WaitFor(ctx, func(val interface{}) bool { return val == "ready" })
+	if err != nil || val != "ready" {
+		t.Fatalf("unexpected result %v, %v", val, err)
+	}
+	if group.MemberCount() != 0 {
+		t.Fatal("waiting member must leave the group")
+	}
+}
+
+// Create new broadcast group.
+// Freeze it, send and unfreeze it.
+func TestFreeze(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	group.Freeze()
+	group.Send("held message")
+	select {
+	case val := <-member.Read:
+		t.Fatalf("value %v delivered while frozen", val)
+	case <-time.After(50 * time.Millisecond):
+	}
+	group.Unfreeze()
+	if val := member.Recv(); val != "held message" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
+
+// Create new broadcast group without a broadcast loop.
+// Check that sending and receiving give up when the context is done.
+func TestSendRecvContext(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := group.SendContext(ctx, "lost"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if _, err := member.RecvContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	go group.Broadcast(0)
+	go group.SendContext(context.Background(), "delivered")
+	if val, err := member.RecvContext(context.Background()); err != nil || val != "delivered" {
+		t.Fatalf("unexpected value %v, %v", val, err)
+	}
+}
+
+// Create new broadcast group with a buffered member.
+// Send a burst without reading and receive it afterwards in order.
+func TestJoinBuffered(t *testing.T) {
+	group := NewGroup()
+	member := group.JoinBuffered(3)
+	go group.Broadcast(0)
+
+	for i := 0; i < 3; i++ {
+		group.Send(i)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(member.Read) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(member.Read) != 3 {
+		t.Fatalf("expected 3 buffered values, got %d", len(member.Read))
+	}
+	for i := 0; i < 3; i++ {
+		if val := member.Recv(); val != i {
+			t.Fatalf("expected %d, got %v", i, val)
+		}
+	}
+}
+
+// Create new broadcast group.
+// Check that TrySend fails without a broadcast loop and succeeds with one.
+func TestTrySend(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	if group.TrySend("lost") {
+		t.Fatal("TrySend must fail without a broadcast loop")
+	}
+
+	go group.Broadcast(0)
+	deadline := time.Now().Add(time.Second)
+	for !group.TrySend("delivered") {
+		if time.Now().After(deadline) {
+			t.Fatal("TrySend did not succeed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if val := member.Recv(); val != "delivered" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
+
+// Create new broadcast group.
+// Poll and wait for a message with bounded receives.
+func TestTryRecvAndRecvTimeout(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	if val, ok := member.TryRecv(); ok {
+		t.Fatalf("unexpected value %v", val)
+	}
+	if _, err := member.RecvTimeout(10 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	go group.Send("bounded")
+	if val, err := member.RecvTimeout(time.Second); err != nil || val != "bounded" {
+		t.Fatalf("unexpected value %v, %v", val, err)
+	}
+}
+
+// Create new broadcast group with a filtered member.
+// Send even and odd numbers and receive only the even ones in order.
+func TestJoinFiltered(t *testing.T) {
+	group := NewGroup()
+	member := group.JoinFiltered(func(val interface{}) bool {
+		return val.(int)%2 == 0
+	})
+	go group.Broadcast(0)
+
+	go func() {
+		for i := 0; i < 6; i++ {
+			group.Send(i)
+		}
+	}()
+	for _, expected := range []int{0, 2, 4} {
+		if val := member.Recv(); val != expected {
+			t.Fatalf("expected %d, got %v", expected, val)
+		}
+	}
+}
+
+// Create new broadcast group with a read-your-writes member.
+// Check that the sender reads its own message and the other one too.
+func TestReadYourWrites(t *testing.T) {
+	group := NewGroup()
+	member1 := group.Join()
+	member1.ReadYourWrites(true)
+	member2 := group.Join()
+	go group.Broadcast(0)
+
+	member1.Send("own write")
+	if val, ok := member1.TryRecv(); !ok || val != "own write" {
+		t.Fatalf("own write not visible, got %v", val)
+	}
+	if val := member2.Recv(); val != "own write" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
+
+// Broadcast to a small group of 4 members and wait until all of them
+// received the message.
+func BenchmarkSmallGroup(b *testing.B) {
+	group := NewGroup()
+	var members []*Member
+	for i := 0; i < 4; i++ {
+		members = append(members, group.Join())
+	}
+	go group.Broadcast(0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		group.Send(i)
+		for _, member := range members {
+			member.Recv()
+		}
+	}
+}
+
+// Create new broadcast group and read a member through the deprecated
+// In pointer of the first upstream version.
+func TestMemberIn(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go group.Send("upstream")
+	if val := <-*member.In; val != "upstream" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
@@ -0,0 +1,326 @@
+// Package bcasttest provides randomized scenarios and invariant checks
+// for testing broadcast groups of package bcast, for example groups
+// configured with custom options.
+package bcasttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafov/bcast"
+)
+
+// OpKind is the kind of a scenario step.
+type OpKind int
+
+const (
+	// Join joins a new member to the group.
+	Join OpKind = iota
+	// Leave removes a member from the group.
+	Leave
+	// Send broadcasts a value to the group.
+	Send
+)
+
+// Op is one step of a scenario. Member is the number of the member
+// joining or leaving, members are numbered in the order they join.
+// Value is the value sent, values grow with every Send.
+type Op struct {
+	Kind   OpKind
+	Member int
+	Value  int
+}
+
+func (op Op) String() string {
+	switch op.Kind {
+	case Join:
+		return fmt.Sprintf("join %d", op.Member)
+	case Leave:
+		return fmt.Sprintf("leave %d", op.Member)
+	default:
+		return fmt.Sprintf("send %d", op.Value)
+	}
+}
+
+// Scenario is a sequence of membership changes and broadcasts.
+type Scenario []Op
+
+// Generate returns a random valid scenario of n steps drawn from r.
+// Members only leave after they joined and every value is sent once.
+func Generate(r *rand.Rand, n int) Scenario {
+	var (
+		s       = make(Scenario, 0, n)
+		joined  []int
+		members int
+		values  int
+	)
+	for len(s) < n {
+		switch k := r.Intn(10); {
+		case k < 3 || len(joined) == 0:
+			s = append(s, Op{Kind: Join, Member: members})
+			joined = append(joined, members)
+			members++
+		case k < 4:
+			i := r.Intn(len(joined))
+			s = append(s, Op{Kind: Leave, Member: joined[i]})
+			joined = append(joined[:i], joined[i+1:]...)
+		default:
+			s = append(s, Op{Kind: Send, Value: values})
+			values++
+		}
+	}
+	return s
+}
+
+// History records what every member received while a scenario was
+// run. Received is indexed by member number and holds only the values
+// sent by the scenario in the order they arrived.
+type History struct {
+	Scenario Scenario
+	Received map[int][]interface{}
+}
+
+// span returns the positions in the scenario where member joined and
+// left. A member still in the group at the end leaves at len(s).
+func (h *History) span(member int) (joined, left int) {
+	joined, left = -1, len(h.Scenario)
+	for i, op := range h.Scenario {
+		if op.Member != member || op.Kind == Send {
+			continue
+		}
+		if op.Kind == Join {
+			joined = i
+		} else {
+			left = i
+		}
+	}
+	return joined, left
+}
+
+// expected returns the values a member still in the group at the end
+// of the scenario must have received.
+func (h *History) expected(member int) []int {
+	joined, left := h.span(member)
+	if left < len(h.Scenario) {
+		return nil
+	}
+	var vals []int
+	for _, op := range h.Scenario[joined+1:] {
+		if op.Kind == Send {
+			vals = append(vals, op.Value)
+		}
+	}
+	return vals
+}
+
+// complete reports whether member received everything it must.
+func (h *History) complete(member int) bool {
+	expected := h.expected(member)
+	got := 0
+	for _, val := range h.Received[member] {
+		if got < len(expected) && val == expected[got] {
+			got++
+		}
+	}
+	return got == len(expected)
+}
+
+// Run executes scenario s against group g and returns what the members
+// received. It runs the broadcast loop of g itself, so g must not be
+// broadcasting yet. Before a member joins, Run waits up to timeout for
+// the values sent so far to be broadcast, so the member gets only the
+// values sent after it joined. After the last step Run waits up to
+// timeout for the remaining members to receive their values, then all
+// of them leave.
+func Run(g *bcast.Group, s Scenario, timeout time.Duration) *History {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- g.Run(ctx) }()
+
+	var (
+		lock       sync.Mutex
+		h          = &History{Scenario: s, Received: make(map[int][]interface{})}
+		members    = make(map[int]*bcast.Member)
+		collectors = make(map[int]chan bool)
+	)
+	leave := func(member int) {
+		members[member].Close()
+		<-collectors[member]
+		delete(members, member)
+	}
+	clock, sent := g.Snapshot().Clock, 0
+	for _, op := range s {
+		switch op.Kind {
+		case Join:
+			// Values sent before the member joins must not reach
+			// it, so let the loop stamp them first.
+			stamped(g, clock+sent, timeout)
+			m := g.Join()
+			finished := make(chan bool)
+			members[op.Member] = m
+			collectors[op.Member] = finished
+			go func(member int) {
+				defer close(finished)
+				for val := range m.Read {
+					// The notice of Close ends the collection.
+					if _, ok := val.(bcast.Message); ok {
+						return
+					}
+					lock.Lock()
+					h.Received[member] = append(h.Received[member], val)
+					lock.Unlock()
+				}
+			}(op.Member)
+		case Leave:
+			leave(op.Member)
+		case Send:
+			g.Send(op.Value)
+			sent++
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for member := range members {
+		for time.Now().Before(deadline) {
+			lock.Lock()
+			complete := h.complete(member)
+			lock.Unlock()
+			if complete {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for member := range members {
+		leave(member)
+	}
+	cancel()
+	<-done
+	return h
+}
+
+// stamped waits up to timeout until the broadcast loop of g stamped
+// the messages before clock. Messages dropped by middleware are never
+// stamped, so the wait may run out.
+func stamped(g *bcast.Group, clock int, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for g.Snapshot().Clock < clock && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Microsecond)
+	}
+}
+
+// Invariant checks a delivery property on a history and describes the
+// first violation found.
+type Invariant func(*History) error
+
+// Ordered requires every member to receive values in the order they
+// were sent and each of them at most once.
+func Ordered(h *History) error {
+	for member, vals := range h.Received {
+		last := -1
+		for _, val := range vals {
+			v, ok := val.(int)
+			if ok && v <= last {
+				return fmt.Errorf("member %d received %d after %d", member, v, last)
+			}
+			if ok {
+				last = v
+			}
+		}
+	}
+	return nil
+}
+
+// NoPhantoms requires every value received by a member to have been
+// sent by the scenario before the member left.
+func NoPhantoms(h *History) error {
+	for member, vals := range h.Received {
+		joined, left := h.span(member)
+		sent := make(map[interface{}]bool)
+		for _, op := range h.Scenario[joined+1 : left] {
+			if op.Kind == Send {
+				sent[op.Value] = true
+			}
+		}
+		for _, val := range vals {
+			if !sent[val] {
+				return fmt.Errorf("member %d received %v which was not sent while it was a member", member, val)
+			}
+		}
+	}
+	return nil
+}
+
+// Complete requires every member still in the group at the end of the
+// scenario to receive all values sent after it joined.
+func Complete(h *History) error {
+	for _, op := range h.Scenario {
+		if op.Kind == Join && !h.complete(op.Member) {
+			return fmt.Errorf("member %d received %v, want %v", op.Member, h.Received[op.Member], h.expected(op.Member))
+		}
+	}
+	return nil
+}
+
+// Invariants are the guarantees of a group with default options.
+var Invariants = []Invariant{Ordered, NoPhantoms, Complete}
+
+// Check returns the violations of the given invariants found in h
+// joined into one error, or nil when h satisfies all of them.
+func Check(h *History, invariants ...Invariant) error {
+	var errs []error
+	for _, invariant := range invariants {
+		if err := invariant(h); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SeedEnv is the environment variable making Quick replay the single
+// scenario of the given seed, as printed on failure.
+const SeedEnv = "BCASTTEST_SEED"
+
+// Seeded returns the scenario of up to steps steps generated from seed.
+// The same seed always gives the same scenario.
+func Seeded(seed int64, steps int) Scenario {
+	r := rand.New(rand.NewSource(seed))
+	return Generate(r, r.Intn(steps)+1)
+}
+
+// Quick runs iterations random scenarios of up to steps steps against
+// fresh groups made by newGroup and fails t with the seed of the
+// scenario and the violations when a history breaks one of the
+// invariants. With SeedEnv set, Quick runs only the scenario of that
+// seed. The scenario is reproduced exactly, the timing of deliveries
+// is up to the scheduler.
+func Quick(t testing.TB, newGroup func() *bcast.Group, iterations, steps int, invariants ...Invariant) {
+	t.Helper()
+	var seeds []int64
+	if env := os.Getenv(SeedEnv); env != "" {
+		seed, err := strconv.ParseInt(env, 10, 64)
+		if err != nil {
+			t.Fatalf("bad %s: %v", SeedEnv, err)
+		}
+		seeds = append(seeds, seed)
+	} else {
+		r := rand.New(rand.NewSource(time.Now().UnixNano()))
+		for i := 0; i < iterations; i++ {
+			seeds = append(seeds, r.Int63())
+		}
+	}
+	for _, seed := range seeds {
+		s := Seeded(seed, steps)
+		h := Run(newGroup(), s, time.Second)
+		if err := Check(h, invariants...); err != nil {
+			t.Fatalf("scenario %v:\n%v\nreplay with %s=%d", s, err, SeedEnv, seed)
+		}
+	}
+}
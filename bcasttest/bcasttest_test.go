@@ -0,0 +1,72 @@
+package bcasttest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/grafov/bcast"
+)
+
+// Run random scenarios against groups with default options.
+func TestQuick(t *testing.T) {
+	Quick(t, func() *bcast.Group { return bcast.NewGroup() }, 20, 50, Invariants...)
+}
+
+// Generate a scenario and check that it is valid.
+func TestGenerate(t *testing.T) {
+	s := Generate(rand.New(rand.NewSource(1)), 100)
+	if len(s) != 100 {
+		t.Fatalf("got %d steps, want 100", len(s))
+	}
+	joined := make(map[int]bool)
+	for _, op := range s {
+		switch op.Kind {
+		case Join:
+			joined[op.Member] = true
+		case Leave:
+			if !joined[op.Member] {
+				t.Fatalf("member %d leaves without being a member", op.Member)
+			}
+			delete(joined, op.Member)
+		}
+	}
+}
+
+// Generate scenarios from the same seed twice.
+func TestSeeded(t *testing.T) {
+	a, b := Seeded(42, 50), Seeded(42, 50)
+	if len(a) != len(b) {
+		t.Fatalf("scenarios of the same seed differ: %v and %v", a, b)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("scenarios of the same seed differ: %v and %v", a, b)
+		}
+	}
+}
+
+// Check that broken histories are reported.
+func TestCheck(t *testing.T) {
+	s := Scenario{{Kind: Join, Member: 0}, {Kind: Send, Value: 0}, {Kind: Send, Value: 1}}
+	for _, received := range [][]interface{}{
+		{1, 0},
+		{0, 1, 2},
+		{0},
+	} {
+		h := &History{Scenario: s, Received: map[int][]interface{}{0: received}}
+		if Check(h, Invariants...) == nil {
+			t.Errorf("history %v must violate the invariants", received)
+		}
+	}
+	h := &History{Scenario: s, Received: map[int][]interface{}{0: {0, 1}}}
+	if err := Check(h, Invariants...); err != nil {
+		t.Fatal(err)
+	}
+
+	// A value sent before a member joined is a phantom for it.
+	s = Scenario{{Kind: Join, Member: 0}, {Kind: Send, Value: 0}, {Kind: Join, Member: 1}, {Kind: Send, Value: 1}}
+	h = &History{Scenario: s, Received: map[int][]interface{}{0: {0, 1}, 1: {0, 1}}}
+	if NoPhantoms(h) == nil {
+		t.Error("value sent before the member joined must be a phantom")
+	}
+}
@@ -0,0 +1,138 @@
+package bcast
+
+import "sync"
+
+// BridgeOption configures a bridge made by Bridge.
+type BridgeOption func(*bridge)
+
+// OneWay makes the bridge forward only from the first group to the
+// second one.
+func OneWay() BridgeOption {
+	return func(br *bridge) {
+		br.oneWay = true
+	}
+}
+
+// Transform makes the bridge forward the payload returned by fn instead
+// of the original one. Payloads for which fn returns false are not
+// forwarded. On a two-way bridge fn applies in both directions. Panics
+// of fn follow the panic policy of the group the payload comes from.
+func Transform(fn func(interface{}) (interface{}, bool)) BridgeOption {
+	return func(br *bridge) {
+		br.transform = fn
+	}
+}
+
+// Bridge forwards the payloads broadcast in group a to group b and,
+// unless OneWay is given, the payloads broadcast in b to a. Topics are
+// kept, messages sent to single members or tags are not forwarded. The
+// bridge joins each group as a member, so with a Distribution other
+// than Broadcast it takes its share of the messages like any other
+// member.
+//
+// Every message remembers the groups it passed, and a bridge never
+// forwards a message into one of them, so groups may be bridged into
+// rings and meshes without messages going round forever. A message
+// reaching a group over several paths is delivered once per path
+// though. The end of stream of a group is not forwarded but stops
+// forwarding from that group.
+//
+// The returned func stops the bridge and removes its members from both
+// groups.
+func Bridge(a, b *Group, options ...BridgeOption) (stop func()) {
+	br := &bridge{done: make(chan struct{})}
+	for _, option := range options {
+		option(br)
+	}
+	join := func(g *Group) *Member {
+		return g.add(make(chan interface{}), func(m *Member) {
+			m.bridge = br
+			m.topics = map[string]bool{">": true}
+		})
+	}
+	ma, mb := join(a), join(b)
+	go br.forward(ma, mb)
+	if !br.oneWay {
+		go br.forward(mb, ma)
+	}
+	br.stop = func() {
+		br.once.Do(func() {
+			close(br.done)
+			a.leave(ma, false)
+			b.leave(mb, false)
+		})
+	}
+	return br.stop
+}
+
+// bridge is the state shared by both directions of a bridge.
+type bridge struct {
+	oneWay    bool
+	transform func(interface{}) (interface{}, bool)
+	closeSend bool
+	done      chan struct{}
+	stop      func()
+	once      sync.Once
+}
+
+// forwarded is what the members of a bridge receive instead of the bare
+// payload.
+type forwarded struct {
+	payload interface{}
+	topic   string
+	hops    []*Group
+}
+
+// forward relays what member from receives to the group of member to
+// until the bridge stops or the group of from reaches the end of
+// stream, which is passed on only by bridges set to closeSend.
+func (br *bridge) forward(from, to *Member) {
+	for {
+		select {
+		case val := <-from.Read:
+			if val == EOS {
+				if br.closeSend {
+					to.group.CloseSend()
+				}
+				return
+			}
+			if f, ok := val.(forwarded); ok {
+				br.relay(f, from, to)
+			}
+		case <-br.done:
+			return
+		}
+	}
+}
+
+// relay sends the payload of f received by member from on behalf of
+// member to unless f already passed the group of to.
+func (br *bridge) relay(f forwarded, from, to *Member) {
+	for _, hop := range f.hops {
+		if hop == to.group {
+			return
+		}
+	}
+	payload := f.payload
+	if br.transform != nil {
+		ok := false
+		if from.group.guard("bridge transform", func() { payload, ok = br.transform(payload) }) {
+			if from.group.panicPolicy == PanicIsolateMember {
+				go br.stop()
+			}
+			return
+		}
+		if !ok {
+			return
+		}
+	}
+	message := to.group.data(to, payload)
+	message.topic = f.topic
+	// The hops are shared by all the members receiving f, so they
+	// must be copied rather than appended to in place.
+	message.hops = append(f.hops[:len(f.hops):len(f.hops)], from.group)
+	select {
+	case to.group.in <- message:
+	case <-br.done:
+	}
+}
@@ -0,0 +1,128 @@
+package bcast
+
+import (
+	"testing"
+	"time"
+)
+
+// Create two broadcast groups with a member each and bridge them.
+// Check that messages pass the bridge both ways.
+func TestBridge(t *testing.T) {
+	a, b := NewGroup(), NewGroup()
+	ma, mb := a.Join(), b.Join()
+	go a.Broadcast(0)
+	go b.Broadcast(0)
+	stop := Bridge(a, b)
+	defer stop()
+
+	go ma.Send("from a")
+	if val, err := mb.RecvTimeout(time.Second); val != "from a" {
+		t.Fatalf("unexpected value %v (%v)", val, err)
+	}
+	go mb.Send("from b")
+	if val, err := ma.RecvTimeout(time.Second); val != "from b" {
+		t.Fatalf("unexpected value %v (%v)", val, err)
+	}
+	if val, ok := ma.TryRecv(); ok {
+		t.Fatalf("message %v came back over the bridge", val)
+	}
+}
+
+// Create two broadcast groups bridged one way with a transform.
+// Check that only transformed messages from the first group pass.
+func TestBridgeOneWayTransform(t *testing.T) {
+	a, b := NewGroup(), NewGroup()
+	ma, mb := a.Join(), b.Join()
+	go a.Broadcast(0)
+	go b.Broadcast(0)
+	stop := Bridge(a, b, OneWay(), Transform(func(val interface{}) (interface{}, bool) {
+		n, ok := val.(int)
+		return n * 10, ok && n > 0
+	}))
+	defer stop()
+
+	go func() {
+		ma.Send(-1)
+		ma.Send(2)
+	}()
+	if val, err := mb.RecvTimeout(time.Second); val != 20 {
+		t.Fatalf("unexpected value %v (%v)", val, err)
+	}
+	go mb.Send(3)
+	if val, err := ma.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("one-way bridge forwarded %v back", val)
+	}
+}
+
+// Create three broadcast groups bridged both ways into a triangle.
+// Check that a message never comes back to its own group.
+func TestBridgeTriangle(t *testing.T) {
+	groups := []*Group{NewGroup(), NewGroup(), NewGroup()}
+	members := make([]*Member, len(groups))
+	for i, g := range groups {
+		members[i] = g.Join()
+		go g.Broadcast(0)
+	}
+	for i := range groups {
+		stop := Bridge(groups[i], groups[(i+1)%len(groups)])
+		defer stop()
+	}
+
+	go members[0].Send("round")
+	for _, m := range members[1:] {
+		if val, err := m.RecvTimeout(time.Second); val != "round" {
+			t.Fatalf("unexpected value %v (%v)", val, err)
+		}
+	}
+	if val, err := members[0].RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("message %v came back to its group", val)
+	}
+}
+
+// Create three broadcast groups bridged one way into a ring.
+// Check that every member gets a message exactly once.
+func TestBridgeRing(t *testing.T) {
+	groups := []*Group{NewGroup(), NewGroup(), NewGroup()}
+	members := make([]*Member, len(groups))
+	for i, g := range groups {
+		members[i] = g.Join()
+		go g.Broadcast(0)
+	}
+	for i := range groups {
+		stop := Bridge(groups[i], groups[(i+1)%len(groups)], OneWay())
+		defer stop()
+	}
+
+	go members[0].Send("round")
+	for _, m := range members[1:] {
+		if val, err := m.RecvTimeout(time.Second); val != "round" {
+			t.Fatalf("unexpected value %v (%v)", val, err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	for i, m := range members {
+		if val, ok := m.TryRecv(); ok {
+			t.Fatalf("member %d got %v again", i, val)
+		}
+	}
+}
+
+// Create two bridged broadcast groups and stop the bridge.
+// Check that the bridge members leave and nothing passes anymore.
+func TestBridgeStop(t *testing.T) {
+	a, b := NewGroup(), NewGroup()
+	ma, mb := a.Join(), b.Join()
+	go a.Broadcast(0)
+	go b.Broadcast(0)
+	stop := Bridge(a, b)
+	stop()
+	stop()
+
+	if a.MemberCount() != 1 || b.MemberCount() != 1 {
+		t.Fatal("bridge members must leave")
+	}
+	go ma.Send("stopped")
+	if val, err := mb.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("stopped bridge forwarded %v", val)
+	}
+}
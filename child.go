@@ -0,0 +1,53 @@
+package bcast
+
+import "context"
+
+// Child returns the child group of g with the given name, creating it
+// with opts and starting its broadcast loop when it does not exist yet.
+// The members of a child receive everything broadcast in g, including
+// the end of stream, and the messages sent within the child, which
+// stay local to it. Children may have children of their own, so scoped
+// broadcast domains share the fan-out of their parents upstream.
+//
+// A child is fed by a one-way Bridge and counts as one member of g.
+// It lives as long as g: closing or shutting down g stops the bridges
+// and the broadcast loops of its children and grandchildren.
+func (g *Group) Child(name string, opts ...Option) *Group {
+	g.childLock.Lock()
+	defer g.childLock.Unlock()
+	child, ok := g.children[name]
+	if !ok {
+		child = NewGroup(opts...)
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			child.Run(ctx)
+		}()
+		stop := Bridge(g, child, OneWay(), func(br *bridge) { br.closeSend = true })
+		g.childStops = append(g.childStops, func() {
+			stop()
+			cancel()
+			<-stopped
+			child.closeChildren()
+		})
+		if g.children == nil {
+			g.children = make(map[string]*Group)
+		}
+		g.children[name] = child
+	}
+	return child
+}
+
+// closeChildren stops the bridges feeding the children of g and their
+// broadcast loops, down to the last grandchild, and waits until the
+// loops returned.
+func (g *Group) closeChildren() {
+	g.childLock.Lock()
+	stops := g.childStops
+	g.children, g.childStops = nil, nil
+	g.childLock.Unlock()
+	for _, stop := range stops {
+		stop()
+	}
+}
@@ -0,0 +1,69 @@
+package bcast
+
+import (
+	"testing"
+	"time"
+)
+
+// Create new broadcast group with two children and a grandchild.
+// Check that parent traffic reaches all of them and local traffic
+// stays local.
+func TestChild(t *testing.T) {
+	group := NewGroup()
+	go group.Broadcast(0)
+	root := group.Join()
+	eu, us := group.Child("region-eu"), group.Child("region-us")
+	if group.Child("region-eu") != eu {
+		t.Fatal("child must be reused by name")
+	}
+	paris := eu.Child("paris")
+	euMember, euSender, usMember, parisMember := eu.Join(), eu.Join(), us.Join(), paris.Join()
+
+	go root.Send("global")
+	for _, m := range []*Member{euMember, euSender, usMember, parisMember} {
+		if val, err := m.RecvTimeout(time.Second); val != "global" {
+			t.Fatalf("unexpected value %v (%v)", val, err)
+		}
+	}
+
+	go euSender.Send("local")
+	for _, m := range []*Member{euMember, parisMember} {
+		if val, err := m.RecvTimeout(time.Second); val != "local" {
+			t.Fatalf("unexpected value %v (%v)", val, err)
+		}
+	}
+	for _, m := range []*Member{root, usMember} {
+		if val, err := m.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+			t.Fatalf("local message %v escaped its child", val)
+		}
+	}
+
+	go group.CloseSend()
+	if val, err := parisMember.RecvTimeout(time.Second); val != EOS {
+		t.Fatalf("expected EOS, got %v (%v)", val, err)
+	}
+}
+
+// Create new broadcast group with a child and a grandchild and close it.
+// Check that the bridges and loops of both are stopped.
+func TestChildClose(t *testing.T) {
+	group := NewGroup()
+	go group.Broadcast(0)
+	eu := group.Child("region-eu")
+	paris := eu.Child("paris")
+
+	group.Close()
+	if group.MemberCount() != 0 || eu.MemberCount() != 0 || paris.MemberCount() != 0 {
+		t.Fatal("bridge members must leave")
+	}
+	for _, child := range []*Group{eu, paris} {
+		select {
+		case child.close <- true:
+			t.Fatal("child loop must be stopped")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	if group.Child("region-eu") == eu {
+		t.Fatal("closed child must not be reused")
+	}
+}
@@ -0,0 +1,79 @@
+package bcast
+
+import (
+	"context"
+)
+
+// Cond is a condition variable backed by a Group. Unlike sync.Cond it
+// does not need a lock and its waits may be abandoned through a
+// context.
+//
+// A wakeup is never lost between checking a condition and waiting for
+// it when the waiter is prepared before the check:
+//
+//	w := cond.Prepare()
+//	if !ready() {
+//		err = w.Wait(ctx)
+//	}
+type Cond struct {
+	group *Group
+}
+
+// Waiter is a registration to be woken up by a Cond.
+type Waiter struct {
+	group  *Group
+	member *Member
+}
+
+// NewCond creates a new condition variable. Close releases it.
+func NewCond() *Cond {
+	group := NewGroup()
+	go group.Broadcast(0)
+	return &Cond{group: group}
+}
+
+// Close stops the group behind the condition variable.
+func (c *Cond) Close() {
+	c.group.Close()
+}
+
+// Prepare registers a waiter. Every Signal or BroadcastSignal called
+// after Prepare returned may wake it up.
+func (c *Cond) Prepare() *Waiter {
+	return &Waiter{group: c.group, member: c.group.Join()}
+}
+
+// Wait blocks until a signal wakes it up or ctx is done, in which case
+// ctx.Err() is returned. It is a shortcut for Prepare().Wait(ctx).
+func (c *Cond) Wait(ctx context.Context) error {
+	return c.Prepare().Wait(ctx)
+}
+
+// Signal wakes up the longest waiting waiter, if there is any.
+func (c *Cond) Signal() {
+	for _, member := range c.group.Members() {
+		// The waiter is woken up by being removed from the group.
+		if c.group.leave(member, false) == nil {
+			return
+		}
+	}
+}
+
+// BroadcastSignal wakes up all the waiters.
+func (c *Cond) BroadcastSignal() {
+	c.group.Send(nil)
+}
+
+// Wait blocks until a signal wakes the waiter up or ctx is done, in
+// which case ctx.Err() is returned. A waiter may wait only once.
+func (w *Waiter) Wait(ctx context.Context) error {
+	defer w.group.leave(w.member, false)
+	select {
+	case <-w.member.Read:
+		return nil
+	case <-w.member.close:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
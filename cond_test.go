@@ -0,0 +1,41 @@
+package bcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Create new condition variable.
+// Prepare three waiters, signal one of them and then all.
+func TestCond(t *testing.T) {
+	cond := NewCond()
+	defer cond.Close()
+	woken := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		waiter := cond.Prepare()
+		go func() { woken <- waiter.Wait(context.Background()) }()
+	}
+
+	cond.Signal()
+	if err := <-woken; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-woken:
+		t.Fatal("signal must wake up a single waiter")
+	case <-time.After(50 * time.Millisecond):
+	}
+	cond.BroadcastSignal()
+	for i := 0; i < 2; i++ {
+		if err := <-woken; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := cond.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package bcast
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config describes the options of a group declaratively, so services
+// wiring many groups may load them from configuration files, the
+// environment or flags.
+type Config struct {
+	// NilPolicy is one of "allow", "reject" and "wrap". Empty means
+	// "allow".
+	NilPolicy string
+	// Hibernation enables WithHibernation when not zero.
+	Hibernation time.Duration
+	// Relays enables WithRelays when not zero.
+	Relays int
+	// QueueCapacity enables WithQueueCapacity when not zero.
+	QueueCapacity int
+	// Rate enables WithRate with Rate and Burst when not zero.
+	Rate  float64
+	Burst int
+}
+
+var nilPolicies = map[string]NilPolicy{
+	"":       NilAllow,
+	"allow":  NilAllow,
+	"reject": NilReject,
+	"wrap":   NilWrap,
+}
+
+// Validate reports the first invalid field of c.
+func (c *Config) Validate() error {
+	if _, ok := nilPolicies[c.NilPolicy]; !ok {
+		return fmt.Errorf("bcast: unknown nil policy %q", c.NilPolicy)
+	}
+	switch {
+	case c.Hibernation < 0:
+		return fmt.Errorf("bcast: negative hibernation %v", c.Hibernation)
+	case c.Relays < 0:
+		return fmt.Errorf("bcast: negative number of relays %d", c.Relays)
+	case c.QueueCapacity < 0:
+		return fmt.Errorf("bcast: negative queue capacity %d", c.QueueCapacity)
+	case c.Rate < 0:
+		return fmt.Errorf("bcast: negative rate %v", c.Rate)
+	case c.Rate > 0 && c.Burst < 1:
+		return fmt.Errorf("bcast: burst %d must be positive with a rate", c.Burst)
+	}
+	return nil
+}
+
+// Options validates c and returns the options it describes, ready to
+// be passed to NewGroup.
+func (c *Config) Options() ([]Option, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	opts := []Option{WithNilPolicy(nilPolicies[c.NilPolicy])}
+	if c.Hibernation > 0 {
+		opts = append(opts, WithHibernation(c.Hibernation))
+	}
+	if c.Relays > 0 {
+		opts = append(opts, WithRelays(c.Relays))
+	}
+	if c.QueueCapacity > 0 {
+		opts = append(opts, WithQueueCapacity(c.QueueCapacity))
+	}
+	if c.Rate > 0 {
+		opts = append(opts, WithRate(c.Rate, c.Burst))
+	}
+	return opts, nil
+}
+
+// RegisterFlags defines a flag for every field of c in fs, named after
+// the field and prefixed with prefix, e.g. "orders." gives
+// -orders.relays. The current values of c are the defaults.
+func (c *Config) RegisterFlags(fs *flag.FlagSet, prefix string) {
+	fs.StringVar(&c.NilPolicy, prefix+"nil-policy", c.NilPolicy, "nil payload policy: allow, reject or wrap")
+	fs.DurationVar(&c.Hibernation, prefix+"hibernation", c.Hibernation, "idle time before members hibernate")
+	fs.IntVar(&c.Relays, prefix+"relays", c.Relays, "number of fan-out relays")
+	fs.IntVar(&c.QueueCapacity, prefix+"queue-capacity", c.QueueCapacity, "preallocated member queue capacity")
+	fs.Float64Var(&c.Rate, prefix+"rate", c.Rate, "values per second")
+	fs.IntVar(&c.Burst, prefix+"burst", c.Burst, "burst of the rate limit")
+}
+
+// LoadEnv overrides the fields of c with the environment variables
+// named after them and prefixed with prefix, e.g. "ORDERS_" gives
+// ORDERS_RELAYS. Unset variables leave their fields alone.
+func (c *Config) LoadEnv(prefix string) error {
+	var err error
+	lookup := func(name string, parse func(string) error) {
+		if val, ok := os.LookupEnv(prefix + name); ok && err == nil {
+			if perr := parse(val); perr != nil {
+				err = fmt.Errorf("bcast: %s%s: %v", prefix, name, perr)
+			}
+		}
+	}
+	lookup("NIL_POLICY", func(val string) error {
+		c.NilPolicy = val
+		return nil
+	})
+	lookup("HIBERNATION", func(val string) (err error) {
+		c.Hibernation, err = time.ParseDuration(val)
+		return err
+	})
+	lookup("RELAYS", func(val string) (err error) {
+		c.Relays, err = strconv.Atoi(val)
+		return err
+	})
+	lookup("QUEUE_CAPACITY", func(val string) (err error) {
+		c.QueueCapacity, err = strconv.Atoi(val)
+		return err
+	})
+	lookup("RATE", func(val string) (err error) {
+		c.Rate, err = strconv.ParseFloat(val, 64)
+		return err
+	})
+	lookup("BURST", func(val string) (err error) {
+		c.Burst, err = strconv.Atoi(val)
+		return err
+	})
+	return err
+}
+
+// Reconfigured is delivered to the members of a group reconfigured
+// with notification, so they may adapt to the new settings.
+type Reconfigured struct {
+	Config Config
+}
+
+// Reconfigure changes the settings of a running group to those of
+// config. With notify set, the members receive a *Reconfigured after
+// every value sent before. Hibernation is fixed when the group is
+// created and can't be changed.
+func (g *Group) Reconfigure(config Config, notify bool) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	if config.Hibernation != g.hibernate {
+		return fmt.Errorf("bcast: hibernation can't change from %v to %v", g.hibernate, config.Hibernation)
+	}
+	g.nilPolicy.Store(int32(nilPolicies[config.NilPolicy]))
+	switch limiter := g.rate.Load(); {
+	case config.Rate == 0:
+		g.rate.Store(nil)
+	case limiter != nil:
+		limiter.SetRate(config.Rate, config.Burst)
+	default:
+		g.rate.Store(NewLimiter(config.Rate, config.Burst, nil))
+	}
+	g.memberLock.Lock()
+	if config.Relays != g.relays {
+		// The shards were cut for the old number of relays.
+		g.relays, g.shards = config.Relays, nil
+	}
+	g.queueCap = config.QueueCapacity
+	g.memberLock.Unlock()
+	if notify {
+		g.in <- Message{msg_type: MSG_TYPE_CONFIG, payload: &Reconfigured{Config: config}}
+	}
+	return nil
+}
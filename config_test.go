@@ -0,0 +1,97 @@
+package bcast
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+// Load a config from flags and the environment.
+// Check that invalid configs are rejected.
+func TestConfig(t *testing.T) {
+	var config Config
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	config.RegisterFlags(fs, "orders.")
+	if err := fs.Parse([]string{"-orders.relays", "4", "-orders.nil-policy", "wrap"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ORDERS_HIBERNATION", "1s")
+	if err := config.LoadEnv("ORDERS_"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Relays != 4 || config.NilPolicy != "wrap" || config.Hibernation != time.Second {
+		t.Fatalf("unexpected config %+v", config)
+	}
+	opts, err := config.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := NewGroup(opts...)
+	if group.relays != 4 || NilPolicy(group.nilPolicy.Load()) != NilWrap || group.hibernate != time.Second {
+		t.Fatal("options do not match the config")
+	}
+
+	for _, invalid := range []Config{{NilPolicy: "drop"}, {Relays: -1}, {Rate: 10}} {
+		if _, err := invalid.Options(); err == nil {
+			t.Fatalf("config %+v must be invalid", invalid)
+		}
+	}
+	t.Setenv("ORDERS_RELAYS", "many")
+	if err := config.LoadEnv("ORDERS_"); err == nil {
+		t.Fatal("invalid environment must be rejected")
+	}
+}
+
+// Create new broadcast group.
+// Reconfigure it while running and check the notification.
+func TestReconfigure(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	config := Config{NilPolicy: "reject", Relays: 2, Rate: 1000, Burst: 10}
+	go func() {
+		if err := group.Reconfigure(config, true); err != nil {
+			t.Error(err)
+		}
+	}()
+	val, ok := member.Recv().(*Reconfigured)
+	if !ok || val.Config != config {
+		t.Fatalf("unexpected notification %v", val)
+	}
+	if NilPolicy(group.nilPolicy.Load()) != NilReject || group.rate.Load() == nil {
+		t.Fatal("configuration was not applied")
+	}
+	if err := group.Reconfigure(Config{Hibernation: time.Second}, false); err == nil {
+		t.Fatal("hibernation must not change")
+	}
+}
+
+// Create new broadcast group with relays and change their number while
+// it runs. Check that the members are split between the new relays.
+func TestReconfigureRelays(t *testing.T) {
+	group := NewGroup(WithRelays(2))
+	var members []*Member
+	for i := 0; i < 12; i++ {
+		members = append(members, group.Join())
+	}
+	go group.Broadcast(0)
+
+	for i, relays := range []int{2, 4} {
+		if err := group.Reconfigure(Config{Relays: relays}, false); err != nil {
+			t.Fatal(err)
+		}
+		go group.Send(i)
+		for _, member := range members {
+			if val := member.Recv(); val != i {
+				t.Fatalf("unexpected value %v", val)
+			}
+		}
+		group.memberLock.Lock()
+		shards := len(group.relayShards())
+		group.memberLock.Unlock()
+		if shards != relays {
+			t.Fatalf("%d shards for %d relays", shards, relays)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package bcast
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+)
+
+// Distribution defines which members get a message sent to a group.
+type Distribution int
+
+const (
+	// Broadcast delivers every message to all members. It is the
+	// default.
+	Broadcast Distribution = iota
+	// LeastLoaded delivers every message to the one member with the
+	// fewest undelivered messages, turning the group into a work
+	// queue.
+	LeastLoaded
+	// Random delivers every message to one member chosen at random.
+	Random
+	// RoundRobin delivers successive messages to the members in turn,
+	// in the order they joined.
+	RoundRobin
+	// keyed delivers all messages with the same key to the same member,
+	// see SendKeyed.
+	keyed
+)
+
+// WithDistribution sets the distribution of the messages sent to the
+// group with Send. Distribute picks a distribution per message, so the
+// same members may share work and get broadcasts at once.
+func WithDistribution(dist Distribution) Option {
+	return func(g *Group) {
+		g.dist = dist
+	}
+}
+
+// Distribute sends a message to the members of the group chosen by
+// dist.
+func (g *Group) Distribute(dist Distribution, val interface{}) {
+	message := g.data(nil, val)
+	message.dist = dist
+	g.in <- message
+}
+
+// SendKeyed sends a message to one member chosen by key: all messages
+// with the same key go to the same member as long as it stays in the
+// group. Members are chosen by rendezvous hashing, so when membership
+// changes only the keys of the members that left or joined move.
+func (g *Group) SendKeyed(key string, val interface{}) {
+	message := g.data(nil, val)
+	message.dist = keyed
+	message.key = key
+	g.in <- message
+}
+
+// nobody is the recipient of messages no member may get.
+var nobody = &Member{}
+
+// pick chooses the recipient of a message which is not broadcast
+// among the members it may reach and whose filters accept it. It is
+// called with the member lock held. Without candidates the message
+// goes nowhere. The filters run here only, once per member, so the
+// listener of the recipient delivers the message without filtering it
+// again.
+func (g *Group) pick(message *Message) *Member {
+	var candidates []*Member
+	for _, member := range g.members {
+		if member != message.sender && member.subscribed(message.topic) && member.tagged(message.tag) &&
+			member.accepts(message) {
+			candidates = append(candidates, member)
+		}
+	}
+	if len(candidates) == 0 {
+		return nobody
+	}
+	switch message.dist {
+	case Random:
+		return candidates[rand.Intn(len(candidates))]
+	case keyed:
+		var (
+			chosen *Member
+			best   uint64
+		)
+		for _, member := range candidates {
+			if weight := member.weight(message.key); chosen == nil || weight > best {
+				chosen, best = member, weight
+			}
+		}
+		return chosen
+	case RoundRobin:
+		next := candidates[g.turn%len(candidates)]
+		g.turn++
+		return next
+	default:
+		least := candidates[0]
+		for _, member := range candidates[1:] {
+			if member.backlog.Load() < least.backlog.Load() {
+				least = member
+			}
+		}
+		return least
+	}
+}
+
+// weight is the rendezvous hash of key for the member.
+func (m *Member) weight(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	binary.Write(h, binary.LittleEndian, m.id)
+	return h.Sum64()
+}
@@ -0,0 +1,184 @@
+package bcast
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Create new work queue group with a busy and an idle member.
+// Check that the work goes to the idle member.
+func TestLeastLoaded(t *testing.T) {
+	group := NewGroup(WithDistribution(LeastLoaded))
+	busy := group.Join()
+	idle := group.Join()
+	go group.Broadcast(0)
+
+	// Ties go to the member that joined first, which never reads.
+	group.Send("blocking")
+	for i := 0; i < 3; i++ {
+		deadline := time.Now().Add(time.Second)
+		for idle.backlog.Load() > 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		group.Send(i)
+		if val, err := idle.RecvTimeout(time.Second); err != nil || val != i {
+			t.Fatalf("expected %d, got %v, %v", i, val, err)
+		}
+	}
+	if val := busy.Recv(); val != "blocking" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
+
+// Create new broadcast group with two members.
+// Distribute messages at random next to a broadcast.
+func TestDistributeRandom(t *testing.T) {
+	group := NewGroup()
+	members := []*Member{group.Join(), group.Join()}
+	go group.Broadcast(0)
+
+	received := make(chan interface{}, 20)
+	for _, member := range members {
+		go func(member *Member) {
+			for val := range member.Read {
+				received <- val
+			}
+		}(member)
+	}
+	for i := 0; i < 10; i++ {
+		group.Distribute(Random, i)
+	}
+	group.Send("everyone")
+	count := 0
+	for everyone := 0; everyone < 2; {
+		select {
+		case val := <-received:
+			if val == "everyone" {
+				everyone++
+			} else {
+				count++
+			}
+		case <-time.After(time.Second):
+			t.Fatal("messages were lost")
+		}
+	}
+	if count != 10 {
+		t.Fatalf("expected 10 distributed messages, got %d", count)
+	}
+}
+
+// Create new round robin group with three members.
+// Check that successive messages rotate through the members.
+func TestRoundRobin(t *testing.T) {
+	group := NewGroup(WithDistribution(RoundRobin))
+	members := []*Member{group.Join(), group.Join(), group.Join()}
+	go group.Broadcast(0)
+
+	go func() {
+		for i := 0; i < 6; i++ {
+			group.Send(i)
+		}
+	}()
+	for round := 0; round < 2; round++ {
+		for i, member := range members {
+			if val := member.Recv(); val != round*3+i {
+				t.Fatalf("member %d got %v in round %d", i, val, round)
+			}
+		}
+	}
+}
+
+// Create new broadcast group with three members.
+// Check that keys stick to their members when another member leaves.
+func TestSendKeyed(t *testing.T) {
+	group := NewGroup()
+	members := []*Member{group.Join(), group.Join(), group.Join()}
+	go group.Broadcast(0)
+
+	owners := make(map[string]*Member)
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	for _, key := range keys {
+		group.SendKeyed(key, key)
+		owners[key] = receiver(t, members)
+	}
+	leaving := owners["a"]
+	leaving.Close()
+	for _, key := range keys {
+		group.SendKeyed(key, key)
+		if owner := receiver(t, members); owner != owners[key] && owners[key] != leaving {
+			t.Fatalf("key %s moved to another member", key)
+		}
+	}
+}
+
+// receiver returns the member receiving the next message.
+func receiver(t *testing.T, members []*Member) *Member {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		for _, member := range members {
+			if val, ok := member.TryRecv(); ok {
+				if _, control := val.(Message); !control {
+					return member
+				}
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("message was not received")
+	return nil
+}
+
+// Create new work queue group with a plain and a filtered member.
+// Check that no work item goes to a member rejecting it.
+func TestDistributeFiltered(t *testing.T) {
+	for _, dist := range []Distribution{RoundRobin, LeastLoaded, Random, keyed} {
+		group := NewGroup(WithDistribution(dist))
+		plain := group.Join()
+		odd := group.JoinFiltered(func(val interface{}) bool { return val.(int)%2 == 1 })
+		go group.Broadcast(0)
+
+		go func() {
+			for i := 0; i < 4; i++ {
+				if dist == keyed {
+					group.SendKeyed(string(rune('a'+i)), i)
+				} else {
+					group.Send(i)
+				}
+			}
+		}()
+		got := 0
+		for got < 4 {
+			select {
+			case <-plain.Read:
+				got++
+			case val := <-odd.Read:
+				if val.(int)%2 == 0 {
+					t.Fatalf("distribution %d: filtered member got %v", dist, val)
+				}
+				got++
+			case <-time.After(time.Second):
+				t.Fatalf("distribution %d: only %d of 4 items arrived", dist, got)
+			}
+		}
+		group.Close()
+	}
+}
+
+// Create new broadcast group with a member whose filter accepts every
+// other call. Distribute a message to it and check that the filter runs
+// once and the message arrives.
+func TestDistributeFilteredOnce(t *testing.T) {
+	group := NewGroup(WithDistribution(RoundRobin))
+	var calls atomic.Int32
+	member := group.JoinFiltered(func(val interface{}) bool { return calls.Add(1)%2 == 1 })
+	go group.Broadcast(0)
+
+	go group.Send("once")
+	if val, err := member.RecvTimeout(time.Second); val != "once" {
+		t.Fatalf("unexpected value %v (%v)", val, err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("filter ran %d times", n)
+	}
+}
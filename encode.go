@@ -0,0 +1,70 @@
+package bcast
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Codec encodes a payload for the wire, e.g. json.Marshal.
+type Codec func(interface{}) ([]byte, error)
+
+// EncodeCache lets members sharing a codec encode each broadcast value
+// once. Every member of a group receives the very same payload, so a
+// value wrapped by the cache is encoded by the first member asking for
+// its bytes and the others share the result.
+type EncodeCache struct {
+	codec  Codec
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewEncodeCache returns a cache encoding values with codec.
+func NewEncodeCache(codec Codec) *EncodeCache {
+	return &EncodeCache{codec: codec}
+}
+
+// Wrap returns val prepared for shared encoding. Send the result to
+// the group instead of val.
+func (c *EncodeCache) Wrap(val interface{}) *Encoded {
+	return &Encoded{Value: val, cache: c}
+}
+
+// Stats returns the number of Bytes calls served from the cache and
+// the number of encodings made.
+func (c *EncodeCache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// HitRate returns the share of Bytes calls served from the cache.
+func (c *EncodeCache) HitRate() float64 {
+	hits, misses := c.Stats()
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// Encoded is a payload wrapped by an EncodeCache.
+type Encoded struct {
+	Value interface{}
+	cache *EncodeCache
+	once  sync.Once
+	data  []byte
+	err   error
+}
+
+// Bytes returns the encoded value. The codec runs on the first call
+// only, later calls return the same slice, which must not be modified.
+func (e *Encoded) Bytes() ([]byte, error) {
+	encoded := false
+	e.once.Do(func() {
+		e.data, e.err = e.cache.codec(e.Value)
+		encoded = true
+	})
+	if encoded {
+		e.cache.misses.Add(1)
+	} else {
+		e.cache.hits.Add(1)
+	}
+	return e.data, e.err
+}
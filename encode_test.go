@@ -0,0 +1,29 @@
+package bcast
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Create new broadcast group with three members.
+// Send a value wrapped by an encode cache and encode it in every member.
+func TestEncodeCache(t *testing.T) {
+	group := NewGroup()
+	members := []*Member{group.Join(), group.Join(), group.Join()}
+	go group.Broadcast(0)
+	cache := NewEncodeCache(json.Marshal)
+
+	group.Send(cache.Wrap(map[string]int{"a": 1}))
+	for _, member := range members {
+		data, err := member.Recv().(*Encoded).Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `{"a":1}` {
+			t.Fatalf("incorrect encoding %s", data)
+		}
+	}
+	if hits, misses := cache.Stats(); hits != 2 || misses != 1 {
+		t.Fatalf("got %d hits and %d misses, want 2 and 1", hits, misses)
+	}
+}
@@ -0,0 +1,37 @@
+package bcast
+
+import "time"
+
+// SendEnvelope broadcasts env to every one of a Group's members. The
+// time of the envelope is set unless the sender set it already, its
+// sequence number is set by the broadcast loop.
+func (g *Group) SendEnvelope(env *Envelope) {
+	if env.Time.IsZero() {
+		env.Time = time.Now()
+	}
+	g.Send(env)
+}
+
+// SendEnvelope broadcasts env from the member to the other members of
+// its group like Group.SendEnvelope.
+func (m *Member) SendEnvelope(env *Envelope) {
+	if env.Time.IsZero() {
+		env.Time = time.Now()
+	}
+	m.Send(env)
+}
+
+// RecvEnvelope reads one value like Recv and returns it as an
+// envelope. A bare payload comes in an envelope without metadata. A
+// received error, such as EOS or an *ErrorPayload, is returned as the
+// error.
+func (m *Member) RecvEnvelope() (*Envelope, error) {
+	switch val := m.Recv().(type) {
+	case *Envelope:
+		return val, nil
+	case error:
+		return nil, val
+	default:
+		return &Envelope{Payload: val}, nil
+	}
+}
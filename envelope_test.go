@@ -0,0 +1,31 @@
+package bcast
+
+import "testing"
+
+// Create new broadcast group.
+// Send an envelope with headers and a bare payload.
+func TestEnvelope(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go func() {
+		group.Send("bare")
+		group.SendEnvelope(&Envelope{Payload: "wrapped", Headers: map[string]string{"trace": "42"}})
+		group.CloseSend()
+	}()
+	env, err := member.RecvEnvelope()
+	if err != nil || env.Payload != "bare" {
+		t.Fatalf("unexpected envelope %v, %v", env, err)
+	}
+	env, err = member.RecvEnvelope()
+	if err != nil || env.Payload != "wrapped" || env.Headers["trace"] != "42" {
+		t.Fatalf("unexpected envelope %v, %v", env, err)
+	}
+	if env.Time.IsZero() || env.Seq != 1 {
+		t.Fatalf("metadata not set: %v", env)
+	}
+	if _, err := member.RecvEnvelope(); err != EOS {
+		t.Fatalf("expected EOS, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package bcast
+
+import (
+	"context"
+	"log"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// experimentSamples is the number of delivery latencies summarized in
+// every report of the fan-out experiment.
+const experimentSamples = 1024
+
+// WithFanoutExperiment caps the number of members the group delivers
+// to concurrently at workers and reports the distribution of delivery
+// latencies to logf after every experimentSamples deliveries. Running
+// the same workload with different caps shows empirically which
+// concurrency suits it. Delivery goroutines carry the pprof label
+// bcast_fanout_workers so their cost is visible in profiles. A member
+// which stops reading keeps its slot taken, so the mode is meant for
+// experiments rather than for production. Fewer than one worker counts
+// as one, and a nil logf reports to log.Printf.
+func WithFanoutExperiment(workers int, logf func(format string, args ...interface{})) Option {
+	workers = max(workers, 1)
+	if logf == nil {
+		logf = log.Printf
+	}
+	return func(g *Group) {
+		g.experiment = &fanoutExperiment{
+			group:  g,
+			slots:  make(chan struct{}, workers),
+			labels: pprof.Labels("bcast_fanout_workers", strconv.Itoa(workers)),
+			logf:   logf,
+		}
+	}
+}
+
+// fanoutExperiment limits the fan-out concurrency and measures how
+// long deliveries take under the limit.
+type fanoutExperiment struct {
+	group   *Group
+	slots   chan struct{}
+	labels  pprof.LabelSet
+	logf    func(format string, args ...interface{})
+	lock    sync.Mutex
+	samples []time.Duration
+}
+
+// run calls deliver once a slot is free and records its latency,
+// including the wait for the slot.
+func (e *fanoutExperiment) run(deliver func()) {
+	start := time.Now()
+	e.slots <- struct{}{}
+	pprof.Do(context.Background(), e.labels, func(context.Context) {
+		deliver()
+	})
+	<-e.slots
+	e.record(time.Since(start))
+}
+
+func (e *fanoutExperiment) record(latency time.Duration) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.samples = append(e.samples, latency)
+	if len(e.samples) < experimentSamples {
+		return
+	}
+	sort.Slice(e.samples, func(i, j int) bool { return e.samples[i] < e.samples[j] })
+	percentile := func(p int) time.Duration {
+		return e.samples[(len(e.samples)-1)*p/100]
+	}
+	e.group.guard("fan-out experiment", func() {
+		e.logf("bcast: fan-out with %d workers: p50=%v p90=%v p99=%v max=%v",
+			cap(e.slots), percentile(50), percentile(90), percentile(99), e.samples[len(e.samples)-1])
+	})
+	e.samples = e.samples[:0]
+}
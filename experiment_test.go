@@ -0,0 +1,58 @@
+package bcast
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Create new broadcast group with a capped fan-out.
+// Check that the latency distribution is reported.
+func TestFanoutExperiment(t *testing.T) {
+	reports := make(chan string, 1)
+	logf := func(format string, args ...interface{}) {
+		select {
+		case reports <- fmt.Sprintf(format, args...):
+		default:
+		}
+	}
+	group := NewGroup(WithFanoutExperiment(2, logf))
+	var members []*Member
+	for i := 0; i < 4; i++ {
+		members = append(members, group.Join())
+	}
+	go group.Broadcast(0)
+
+	go func() {
+		for i := 0; i < experimentSamples; i++ {
+			group.Send(i)
+		}
+	}()
+	for _, member := range members {
+		go func(member *Member) {
+			for i := 0; i < experimentSamples; i++ {
+				member.Recv()
+			}
+		}(member)
+	}
+	if report := <-reports; !strings.Contains(report, "2 workers") {
+		t.Fatalf("unexpected report %q", report)
+	}
+}
+
+// Create new broadcast group with a fan-out capped at zero workers and
+// no report function. Check that messages are still delivered.
+func TestFanoutExperimentDefaults(t *testing.T) {
+	group := NewGroup(WithFanoutExperiment(0, nil))
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go group.Send("capped")
+	if val, err := member.RecvTimeout(time.Second); val != "capped" {
+		t.Fatalf("unexpected value %v (%v)", val, err)
+	}
+	if cap(group.experiment.slots) != 1 || group.experiment.logf == nil {
+		t.Fatal("defaults were not applied")
+	}
+}
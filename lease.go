@@ -0,0 +1,77 @@
+package bcast
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrExpired is delivered to a member joined with JoinFor once its
+// time is up. No more values follow it.
+var ErrExpired = errors.New("bcast: membership expired")
+
+// JoinFor works like Join but the member is removed from the group
+// after d and then receives ErrExpired. The notification waits for a
+// reader for another d at most, so a forgotten member is cleaned up
+// completely.
+func (g *Group) JoinFor(d time.Duration) *Member {
+	member := g.Join()
+	go func() {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-member.close:
+			return
+		}
+		if g.leave(member, false) != nil {
+			return
+		}
+		timer.Reset(d)
+		select {
+		case member.Read <- ErrExpired:
+		case <-timer.C:
+		}
+	}()
+	return member
+}
+
+// JoinLease works like Join but the member holds its membership on a
+// lease: unless Renew is called at least every ttl, the member is
+// evicted from the group. It suits members driven by other processes
+// which may die without leaving.
+func (g *Group) JoinLease(ttl time.Duration) *Member {
+	member := g.Join()
+	member.renew = make(chan struct{}, 1)
+	go func() {
+		timer := time.NewTimer(ttl)
+		defer timer.Stop()
+		for {
+			select {
+			case <-member.renew:
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(ttl)
+			case <-timer.C:
+				g.leave(member, false)
+				return
+			case <-member.close:
+				return
+			}
+		}
+	}()
+	return member
+}
+
+// Renew extends the lease of a member joined with JoinLease by its
+// ttl. It reports false for members without a lease.
+func (m *Member) Renew() bool {
+	if m.renew == nil {
+		return false
+	}
+	select {
+	case m.renew <- struct{}{}:
+	default:
+	}
+	return true
+}
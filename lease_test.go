@@ -0,0 +1,49 @@
+package bcast
+
+import (
+	"testing"
+	"time"
+)
+
+// Create new broadcast group with two leased members.
+// Renew one lease and let the other expire.
+func TestJoinLease(t *testing.T) {
+	group := NewGroup()
+	renewed := group.JoinLease(50 * time.Millisecond)
+	group.JoinLease(50 * time.Millisecond)
+	go group.Broadcast(0)
+
+	deadline := time.Now().Add(time.Second)
+	for group.MemberCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expired member was not evicted")
+		}
+		renewed.Renew()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if members := group.Members(); members[0] != renewed {
+		t.Fatal("renewed member was evicted")
+	}
+	if group.Join().Renew() {
+		t.Fatal("member without a lease must not renew")
+	}
+}
+
+// Create new broadcast group with a time-boxed member.
+// Check that it gets a message in time and the expiry afterwards.
+func TestJoinFor(t *testing.T) {
+	group := NewGroup()
+	member := group.JoinFor(50 * time.Millisecond)
+	go group.Broadcast(0)
+
+	go group.Send("in time")
+	if val := member.Recv(); val != "in time" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	if val := member.Recv(); val != ErrExpired {
+		t.Fatalf("expected ErrExpired, got %v", val)
+	}
+	if group.MemberCount() != 0 {
+		t.Fatal("expired member must leave")
+	}
+}
@@ -0,0 +1,186 @@
+package bcast
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket limiting the rate of values sent to the
+// groups it is attached to with WithLimiter. A single Limiter may be
+// shared by many groups to cap the traffic of the whole process, while
+// a group with a Limiter of its own gets a separate allowance.
+type Limiter struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	cost   func(interface{}) int
+}
+
+// NewLimiter returns a Limiter refilling rate tokens per second up to
+// burst. Each value sent costs the number of tokens reported by cost,
+// so a cost returning the encoded size limits bytes per second. A nil
+// cost charges one token per value, limiting values per second, and so
+// does a cost which panicked under a policy recovering from panics.
+//
+// A rate of 0 or less never refills the bucket: once the burst is
+// spent, TrySend and the droppable sends fail, SendContext waits until
+// its ctx is done and the other sends wait forever.
+func NewLimiter(rate float64, burst int, cost func(interface{}) int) *Limiter {
+	return &Limiter{
+		rate:   max(rate, 0),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		cost:   cost,
+	}
+}
+
+// SetRate changes the refill rate and the burst of the bucket, with
+// rates of 0 or less meaning no refill like for NewLimiter. Sends
+// already waiting keep waiting as long as the old rate told them to.
+func (l *Limiter) SetRate(rate float64, burst int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.refill()
+	l.rate = max(rate, 0)
+	l.burst = float64(burst)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// WithLimiter makes every send to the group wait for tokens of all
+// the given limiters. SendContext waits only until its ctx is done,
+// while TrySend and the droppable sends never wait but fail when a
+// limiter lacks tokens; tokens are given back for values not sent.
+// Errors and the end of stream are not limited.
+func WithLimiter(limiters ...*Limiter) Option {
+	return func(g *Group) {
+		g.limiters = append(g.limiters, limiters...)
+	}
+}
+
+// costOf returns the number of tokens val costs.
+func (l *Limiter) costOf(val interface{}) float64 {
+	if l.cost == nil {
+		return 1
+	}
+	return float64(l.cost(val))
+}
+
+// refill adds the tokens earned since the last call. The caller holds
+// the lock.
+func (l *Limiter) refill() {
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// wait takes cost tokens from the bucket, sleeping while the bucket is
+// in debt or until ctx is done. Tokens are given back when ctx ends the
+// wait.
+func (l *Limiter) wait(ctx context.Context, cost float64) error {
+	l.lock.Lock()
+	l.refill()
+	l.tokens -= cost
+	debt, rate := -l.tokens, l.rate
+	l.lock.Unlock()
+	if debt <= 0 {
+		return nil
+	}
+	// Without a refill the debt is never paid and only ctx ends the
+	// wait.
+	var paid <-chan time.Time
+	if rate > 0 {
+		timer := time.NewTimer(time.Duration(debt / rate * float64(time.Second)))
+		defer timer.Stop()
+		paid = timer.C
+	}
+	select {
+	case <-paid:
+		return nil
+	case <-ctx.Done():
+		l.give(cost)
+		return ctx.Err()
+	}
+}
+
+// take takes cost tokens from the bucket if it holds them and reports
+// whether it did, without ever waiting.
+func (l *Limiter) take(cost float64) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.refill()
+	if l.tokens < cost {
+		return false
+	}
+	l.tokens -= cost
+	return true
+}
+
+// give puts back cost tokens taken for a value which was not sent.
+func (l *Limiter) give(cost float64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.tokens += cost
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// errLimited is returned by reserve when a bucket lacks the tokens for
+// a value which may not wait.
+var errLimited = errors.New("bcast: rate limited")
+
+// charge is the tokens a value took from one limiter.
+type charge struct {
+	limiter *Limiter
+	cost    float64
+}
+
+// charges are the tokens a value took from the limiters of a group.
+type charges []charge
+
+// refund gives the tokens back.
+func (c charges) refund() {
+	for _, charge := range c {
+		charge.limiter.give(charge.cost)
+	}
+}
+
+// reserve takes the tokens for val from every limiter of the group.
+// With wait it waits for tokens until ctx is done, otherwise it fails
+// with errLimited as soon as a bucket lacks them. Either way nothing
+// stays taken on failure. The tokens taken are returned so they can be
+// refunded when the value is not sent after all.
+func (g *Group) reserve(ctx context.Context, val interface{}, wait bool) (charges, error) {
+	limiters := g.limiters
+	if limiter := g.rate.Load(); limiter != nil {
+		limiters = append(limiters[:len(limiters):len(limiters)], limiter)
+	}
+	taken := make(charges, 0, len(limiters))
+	for _, limiter := range limiters {
+		var cost float64
+		if g.guard("limiter cost", func() { cost = limiter.costOf(val) }) {
+			cost = 1
+		}
+		if wait {
+			if err := limiter.wait(ctx, cost); err != nil {
+				taken.refund()
+				return nil, err
+			}
+		} else if !limiter.take(cost) {
+			taken.refund()
+			return nil, errLimited
+		}
+		taken = append(taken, charge{limiter, cost})
+	}
+	return taken, nil
+}
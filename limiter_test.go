@@ -0,0 +1,99 @@
+package bcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Create two broadcast groups sharing a limiter and one with its own.
+// Check that the shared limiter spans both groups only.
+func TestLimiter(t *testing.T) {
+	shared := NewLimiter(100, 1, nil)
+	group1 := NewGroup(WithLimiter(shared))
+	group2 := NewGroup(WithLimiter(shared))
+	group3 := NewGroup(WithLimiter(NewLimiter(100, 10, nil)))
+	for _, group := range []*Group{group1, group2, group3} {
+		go group.Broadcast(0)
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		group1.Send(i)
+		group2.Send(i)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("6 sends at 100/s took only %v", elapsed)
+	}
+	start = time.Now()
+	for i := 0; i < 6; i++ {
+		group3.Send(i)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Fatalf("sends within the burst took %v", elapsed)
+	}
+}
+
+// Create new broadcast group with an exhausted limiter.
+// Check that non-blocking and cancellable sends don't wait for it and
+// don't use up tokens when they fail.
+func TestLimiterNonBlocking(t *testing.T) {
+	limiter := NewLimiter(1, 1, nil)
+	group := NewGroup(WithLimiter(limiter))
+	member := group.Join()
+	go group.Broadcast(0)
+
+	group.Send("first")
+	member.Recv()
+	start := time.Now()
+	if group.TrySend("second") {
+		t.Fatal("TrySend must fail without tokens")
+	}
+	group.SendDroppable("third")
+	member.SendDroppable("fourth")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := group.SendContext(ctx, "fifth"); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Fatalf("sends waited %v for the limiter", elapsed)
+	}
+
+	// Sends refused by a stopped loop give their tokens back.
+	idle := NewGroup(WithLimiter(NewLimiter(1, 1, nil)))
+	for i := 0; i < 3; i++ {
+		if idle.TrySend(i) {
+			t.Fatal("TrySend must fail without a loop")
+		}
+	}
+	go idle.Broadcast(0)
+	start = time.Now()
+	idle.Send("after")
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Fatalf("failed sends used up tokens, Send waited %v", elapsed)
+	}
+}
+
+// Create new broadcast group with a limiter which never refills.
+// Check that sends past the burst wait for their ctx instead of passing.
+func TestLimiterZeroRate(t *testing.T) {
+	group := NewGroup(WithLimiter(NewLimiter(0, 1, nil)))
+	member := group.Join()
+	go group.Broadcast(0)
+
+	group.Send("burst")
+	member.Recv()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := group.SendContext(ctx, "starved"); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("send passed an empty bucket after %v", elapsed)
+	}
+	if group.TrySend("starved") {
+		t.Fatal("TrySend must fail without a refill")
+	}
+}
@@ -0,0 +1,66 @@
+package bcast
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// memberState is the diagnostic representation of a member.
+type memberState struct {
+	ID             uint64   `json:"id"`
+	Name           string   `json:"name,omitempty"`
+	Clock          int      `json:"clock"`
+	Backlog        int64    `json:"backlog"`
+	BestEffort     bool     `json:"best_effort,omitempty"`
+	Topics         []string `json:"topics,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Filtered       bool     `json:"filtered,omitempty"`
+	Leased         bool     `json:"leased,omitempty"`
+	ReadYourWrites bool     `json:"read_your_writes,omitempty"`
+	Senders        bool     `json:"senders,omitempty"`
+	Left           bool     `json:"left,omitempty"`
+}
+
+// MarshalJSON describes the member for logs and debug endpoints: its
+// ID and name, its clock, the number of messages not yet delivered to
+// it, its subscriptions and tags and the options it was set up with.
+// Payloads and channels are never included. The member keeps running
+// while it is described, so the clock is derived from the clock of the
+// group and the backlog and may be off by the messages in flight.
+func (m *Member) MarshalJSON() ([]byte, error) {
+	backlog := m.backlog.Load()
+	state := memberState{
+		ID:             m.id,
+		Name:           m.Name(),
+		Clock:          int(m.group.clock.Load() - backlog),
+		Backlog:        backlog,
+		BestEffort:     Tier(m.tier.Load()) == TierBestEffort,
+		Filtered:       m.filter != nil,
+		Leased:         m.renew != nil,
+		ReadYourWrites: m.readOwn.Load(),
+		Senders:        m.withSender.Load(),
+	}
+	m.topicLock.RLock()
+	state.Topics = sortedKeys(m.topics)
+	state.Tags = sortedKeys(m.tags)
+	m.topicLock.RUnlock()
+	select {
+	case <-m.close:
+		state.Left = true
+	default:
+	}
+	return json.Marshal(state)
+}
+
+// sortedKeys returns the keys of set in order, or nil for an empty set.
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
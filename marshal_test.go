@@ -0,0 +1,48 @@
+package bcast
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Create new broadcast group with a named member holding messages.
+// Check the diagnostic JSON of the member.
+func TestMemberMarshalJSON(t *testing.T) {
+	group := NewGroup()
+	member, err := group.JoinNamed("worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	member.Subscribe("jobs.>")
+	member.Tag("eu")
+	member.SetTier(TierBestEffort)
+	go group.Broadcast(0)
+
+	group.Send("secret")
+	deadline := time.Now().Add(time.Second)
+	for member.backlog.Load() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	data, err := json.Marshal(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state map[string]interface{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state["name"] != "worker" || state["backlog"] != 1.0 || state["clock"] != 0.0 || state["best_effort"] != true {
+		t.Fatalf("unexpected state %s", data)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Fatalf("payload leaked into %s", data)
+	}
+
+	member.Close()
+	data, _ = json.Marshal(member)
+	if !strings.Contains(string(data), `"left":true`) {
+		t.Fatalf("left member described as %s", data)
+	}
+}
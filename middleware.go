@@ -0,0 +1,151 @@
+package bcast
+
+import (
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// Middleware inspects every value broadcast to a group before its
+// fan-out. It returns the message to broadcast, possibly changed with
+// WithPayload, and false to drop the message instead.
+type Middleware func(Message) (Message, bool)
+
+// Use appends middleware to the chain run by the broadcast loop on
+// every value sent to the group, in the order they were added. Errors
+// and control messages skip the chain. Middleware runs on the loop
+// itself, so it should be quick.
+func (g *Group) Use(middleware ...Middleware) {
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	chain := make([]Middleware, 0, len(g.middleware)+len(middleware))
+	g.middleware = append(append(chain, g.middleware...), middleware...)
+}
+
+// Sampler returns middleware passing up to n payloads per minute to
+// sample, e.g. to detect schema drift, without joining the group. The
+// callback runs on its own goroutine and never holds the broadcast
+// back; payloads beyond the quota are not sampled but still broadcast.
+// Panics of sample follow the panic policy of the group.
+func Sampler(n int, sample func(interface{})) Middleware {
+	var (
+		lock    sync.Mutex
+		window  time.Time
+		sampled int
+	)
+	return func(message Message) (Message, bool) {
+		lock.Lock()
+		now := time.Now()
+		if now.Sub(window) >= time.Minute {
+			window = now
+			sampled = 0
+		}
+		take := sampled < n
+		if take {
+			sampled++
+		}
+		lock.Unlock()
+		if take {
+			go message.group.guard("sampler", func() { sample(message.payload) })
+		}
+		return message, true
+	}
+}
+
+// Shedding returns middleware tightening the delivery under pressure:
+// while underPressure reports true, best-effort members get the
+// messages at most once, dropping those they are not ready to read at
+// once, instead of only once they fell behind. Critical members are
+// not affected. The delivery is restored once the pressure is gone.
+func Shedding(underPressure func() bool) Middleware {
+	return func(message Message) (Message, bool) {
+		message.shed = underPressure()
+		return message, true
+	}
+}
+
+// HeapAbove returns a pressure signal for Shedding reporting whether
+// the live heap exceeds limit bytes. The heap is sampled at most every
+// 100ms.
+func HeapAbove(limit uint64) func() bool {
+	var (
+		lock     sync.Mutex
+		sampled  time.Time
+		pressure bool
+		sample   = []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	)
+	return func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		if time.Since(sampled) >= 100*time.Millisecond {
+			metrics.Read(sample)
+			pressure = sample[0].Value.Uint64() > limit
+			sampled = time.Now()
+		}
+		return pressure
+	}
+}
+
+// Payload returns the value carried by the message.
+func (m Message) Payload() interface{} {
+	return m.payload
+}
+
+// Sender returns the member that sent the message or nil when it was
+// sent to the group directly.
+func (m Message) Sender() *Member {
+	return m.sender
+}
+
+// Topic returns the topic the message was sent to or "" for the group
+// itself.
+func (m Message) Topic() string {
+	return m.topic
+}
+
+// WithPayload returns a copy of the message carrying val instead.
+func (m Message) WithPayload(val interface{}) Message {
+	m.payload = val
+	return m
+}
+
+// intercept runs the middleware chain on the batch and returns the
+// messages left to broadcast.
+func (g *Group) intercept(batch []Message) []Message {
+	g.memberLock.Lock()
+	chain := g.middleware
+	g.memberLock.Unlock()
+	if len(chain) == 0 && g.authorize == nil {
+		return batch
+	}
+	// Rejection notices replace the messages they reject, so kept
+	// never overtakes the message being intercepted.
+	kept := batch[:0]
+next:
+	for _, message := range batch {
+		if message.msg_type == MSG_TYPE_DATA && g.authorize != nil {
+			notice, ok := g.authorized(message)
+			if !ok {
+				if notice.to != nil {
+					kept = append(kept, notice)
+				}
+				continue
+			}
+		}
+		if message.msg_type == MSG_TYPE_DATA {
+			message.group = g
+			for _, middleware := range chain {
+				ok := false
+				if g.guard("middleware", func() { message, ok = middleware(message) }) {
+					g.isolate(message.sender)
+				} else if ok {
+					continue
+				}
+				message.report.expect(0)
+				continue next
+			}
+		}
+		kept = append(kept, message)
+	}
+	return kept
+}
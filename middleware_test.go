@@ -0,0 +1,88 @@
+package bcast
+
+import (
+	"testing"
+	"time"
+)
+
+// Create new broadcast group with middleware dropping odd numbers and
+// doubling the others.
+func TestMiddleware(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	group.Use(func(message Message) (Message, bool) {
+		return message, message.Payload().(int)%2 == 0
+	}, func(message Message) (Message, bool) {
+		return message.WithPayload(message.Payload().(int) * 2), true
+	})
+	go group.Broadcast(0)
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			group.Send(i)
+		}
+	}()
+	for _, expected := range []int{0, 4, 8} {
+		if val := member.Recv(); val != expected {
+			t.Fatalf("expected %d, got %v", expected, val)
+		}
+	}
+}
+
+// Create new broadcast group sampling two payloads per minute.
+// Send five values and check that two were sampled.
+func TestSampler(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	samples := make(chan interface{}, 5)
+	group.Use(Sampler(2, func(val interface{}) { samples <- val }))
+	go group.Broadcast(0)
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			group.Send(i)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		member.Recv()
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-samples:
+		case <-time.After(time.Second):
+			t.Fatal("payload was not sampled")
+		}
+	}
+	select {
+	case val := <-samples:
+		t.Fatalf("payload %v sampled beyond the quota", val)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// Create new broadcast group shedding under pressure with a critical
+// and a best-effort member that are both not ready to read.
+func TestShedding(t *testing.T) {
+	group := NewGroup()
+	critical := group.Join()
+	bestEffort := group.Join()
+	bestEffort.SetTier(TierBestEffort)
+	pressure := true
+	group.Use(Shedding(func() bool { return pressure }))
+	go group.Broadcast(0)
+
+	report := group.SendTracked("shed")
+	deadline := time.Now().Add(time.Second)
+	for report.Dropped() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if val := critical.Recv(); val != "shed" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	if report.Dropped() != 1 {
+		t.Fatalf("best-effort member must drop under pressure, %d dropped", report.Dropped())
+	}
+	if HeapAbove(1 << 62)() {
+		t.Fatal("heap can't be that large")
+	}
+}
@@ -0,0 +1,42 @@
+package bcast
+
+import "errors"
+
+// ErrNameTaken is returned by JoinNamed when a member of the group
+// already has the name.
+var ErrNameTaken = errors.New("bcast: member name taken")
+
+// JoinNamed works like Join but gives the member a name unique within
+// the group, so it can be found with MemberByName without holding its
+// pointer. The name is free again once the member left.
+func (g *Group) JoinNamed(name string) (*Member, error) {
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	if _, ok := g.names[name]; ok {
+		return nil, ErrNameTaken
+	}
+	member := g.addLocked(make(chan interface{}), func(m *Member) {
+		m.name = name
+	})
+	if g.names == nil {
+		g.names = make(map[string]*Member)
+	}
+	g.names[name] = member
+	return member, nil
+}
+
+// MemberByName returns the member of the group with the given name or
+// nil when there is none.
+func (g *Group) MemberByName(name string) *Member {
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	return g.names[name]
+}
+
+// Name returns the name the member joined with, empty for members
+// joined without a name.
+func (m *Member) Name() string {
+	m.group.memberLock.Lock()
+	defer m.group.memberLock.Unlock()
+	return m.name
+}
@@ -0,0 +1,42 @@
+package bcast
+
+import "testing"
+
+// Create new broadcast group with a named member.
+// Look it up, reject a duplicate name and free the name on leave.
+func TestJoinNamed(t *testing.T) {
+	group := NewGroup()
+	worker, err := group.JoinNamed("worker-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.MemberByName("worker-3") != worker || worker.Name() != "worker-3" {
+		t.Fatal("named member not found")
+	}
+	if _, err := group.JoinNamed("worker-3"); err != ErrNameTaken {
+		t.Fatalf("expected ErrNameTaken, got %v", err)
+	}
+	if group.MemberCount() != 1 {
+		t.Fatalf("expected 1 member, got %d", group.MemberCount())
+	}
+	group.Leave(group.MemberByName("worker-3"))
+	if group.MemberByName("worker-3") != nil {
+		t.Fatal("name must be freed on leave")
+	}
+}
+
+// Create new broadcast group with a named member and join its name again.
+// Check that the rejected join never touches the members.
+func TestJoinNamedTaken(t *testing.T) {
+	group := NewGroup()
+	if _, err := group.JoinNamed("worker-3"); err != nil {
+		t.Fatal(err)
+	}
+	epoch, lastID := group.epoch, group.lastID
+	if _, err := group.JoinNamed("worker-3"); err != ErrNameTaken {
+		t.Fatalf("expected ErrNameTaken, got %v", err)
+	}
+	if group.epoch != epoch || group.lastID != lastID {
+		t.Fatal("a member joined under a taken name")
+	}
+}
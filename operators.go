@@ -0,0 +1,393 @@
+package bcast
+
+import (
+	"container/heap"
+	"time"
+)
+
+// TeePolicy describes how a single branch of a Tee absorbs traffic
+// from the source group.
+type TeePolicy struct {
+	// Buffer is the number of messages queued for the branch before
+	// the branch starts to push back (or drop, see Drop).
+	Buffer int
+	// Drop discards messages for the branch while its buffer is full
+	// instead of blocking the other branches.
+	Drop bool
+}
+
+// Tee splits the traffic of group g into n derived groups. Each
+// derived group gets its own broadcast loop and is fed through its own
+// queue configured by the matching element of policies (branches
+// without a policy are unbuffered and blocking). A slow branch with a
+// dropping policy therefore never holds back the others.
+//
+// Derived groups are meant to be read from: messages sent to them by
+// their members are not propagated back to g. When g reaches the end
+// of stream, CloseSend is called on every derived group.
+func Tee(g *Group, n int, policies ...TeePolicy) []*Group {
+	src := g.Join()
+	outs := make([]*Group, n)
+	queues := make([]chan interface{}, n)
+	drop := make([]bool, n)
+	for i := range outs {
+		var policy TeePolicy
+		if i < len(policies) {
+			policy = policies[i]
+		}
+		out := NewGroup()
+		queue := make(chan interface{}, policy.Buffer)
+		go out.Broadcast(0)
+		go func() {
+			for val := range queue {
+				out.Send(val)
+			}
+			out.CloseSend()
+		}()
+		outs[i] = out
+		queues[i] = queue
+		drop[i] = policy.Drop
+	}
+	go func() {
+		for {
+			val := src.Recv()
+			if val == EOS {
+				for _, queue := range queues {
+					close(queue)
+				}
+				src.Close()
+				return
+			}
+			for i, queue := range queues {
+				if !drop[i] {
+					queue <- val
+					continue
+				}
+				select {
+				case queue <- val:
+				default:
+				}
+			}
+		}
+	}()
+	return outs
+}
+
+// minTick is the shortest interval of the tickers driving the
+// time-based operators.
+const minTick = time.Millisecond
+
+// tick returns d, but at least minTick, as time.NewTicker panics on
+// intervals which are not positive.
+func tick(d time.Duration) time.Duration {
+	return max(d, minTick)
+}
+
+// Reducer folds the payloads collected during one window into a single
+// aggregate. A nil aggregate is not emitted.
+type Reducer func(vals []interface{}) interface{}
+
+// Count is a Reducer emitting the number of payloads in the window.
+func Count(vals []interface{}) interface{} {
+	return len(vals)
+}
+
+// Sum is a Reducer emitting the sum of the numeric payloads in the
+// window as float64. Non-numeric payloads are ignored.
+func Sum(vals []interface{}) interface{} {
+	var sum float64
+	for _, val := range vals {
+		switch v := val.(type) {
+		case int:
+			sum += float64(v)
+		case int32:
+			sum += float64(v)
+		case int64:
+			sum += float64(v)
+		case uint:
+			sum += float64(v)
+		case uint32:
+			sum += float64(v)
+		case uint64:
+			sum += float64(v)
+		case float32:
+			sum += float64(v)
+		case float64:
+			sum += v
+		}
+	}
+	return sum
+}
+
+// Window collects everything member m receives into tumbling windows
+// of length d and sends the result of reduce for every window to
+// group dst. Any func with the Reducer signature may be used for
+// custom aggregates. The last, possibly partial, window is emitted
+// when m receives EOS. Empty windows emit nothing, so an idle member
+// sends nothing to dst. Windows shorter than minTick last minTick.
+func Window(m *Member, d time.Duration, reduce Reducer, dst *Group) {
+	go func() {
+		ticker := time.NewTicker(tick(d))
+		defer ticker.Stop()
+		var vals []interface{}
+		for {
+			select {
+			case val := <-m.Read:
+				if val == EOS {
+					if len(vals) > 0 {
+						if aggregate := reduce(vals); aggregate != nil {
+							dst.Send(aggregate)
+						}
+					}
+					return
+				}
+				vals = append(vals, val)
+			case <-ticker.C:
+				if len(vals) == 0 {
+					continue
+				}
+				if aggregate := reduce(vals); aggregate != nil {
+					dst.Send(aggregate)
+				}
+				vals = nil
+			}
+		}
+	}()
+}
+
+// Debounce returns a channel that receives a value from member m only
+// after no newer value arrived for d. Bursts collapse to their last
+// value. The channel is closed once m receives EOS.
+func Debounce(m *Member, d time.Duration) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		timer := time.NewTimer(d)
+		timer.Stop()
+		var (
+			last    interface{}
+			pending bool
+		)
+		for {
+			select {
+			case val := <-m.Read:
+				if val == EOS {
+					if pending {
+						out <- last
+					}
+					close(out)
+					return
+				}
+				last = val
+				pending = true
+				timer.Reset(d)
+			case <-timer.C:
+				out <- last
+				pending = false
+			}
+		}
+	}()
+	return out
+}
+
+// Throttle returns a channel that receives at most one value from
+// member m per interval d. The first value of a burst passes
+// immediately, the latest suppressed one is delivered when the
+// interval ends. The channel is closed once m receives EOS.
+func Throttle(m *Member, d time.Duration) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		var (
+			interval <-chan time.Time
+			last     interface{}
+			pending  bool
+		)
+		for {
+			select {
+			case val := <-m.Read:
+				if val == EOS {
+					if pending {
+						out <- last
+					}
+					close(out)
+					return
+				}
+				if interval == nil {
+					out <- val
+					interval = time.After(d)
+					continue
+				}
+				last = val
+				pending = true
+			case <-interval:
+				interval = nil
+				if pending {
+					out <- last
+					pending = false
+					interval = time.After(d)
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// MergeOrdered joins groups a and b and returns a channel receiving the
+// payloads of both interleaved by the timestamp reported by ts. A
+// payload is held back for at most window so that a late payload of
+// the other group can still be put in front of it. The channel is
+// closed once both groups reached the end of stream, and the members
+// joined to a and b leave their groups then.
+func MergeOrdered(a, b *Group, ts func(interface{}) time.Time, window time.Duration) <-chan interface{} {
+	out := make(chan interface{})
+	ma, mb := a.Join(), b.Join()
+	go func() {
+		orderByTime(out, ts, window, ma, mb)
+		a.leave(ma, false)
+		b.leave(mb, false)
+	}()
+	return out
+}
+
+// EventTimeOrder returns a channel receiving what member m receives,
+// reordered by the event time reported by ts instead of the send
+// order. A payload waits at most lateness for older ones to arrive, a
+// payload arriving later than that is passed on out of order. The
+// channel is closed once m receives EOS.
+func EventTimeOrder(m *Member, ts func(interface{}) time.Time, lateness time.Duration) <-chan interface{} {
+	out := make(chan interface{})
+	go orderByTime(out, ts, lateness, m)
+	return out
+}
+
+// timedPayload is a payload waiting in the reordering buffer of
+// orderByTime.
+type timedPayload struct {
+	val     interface{}
+	arrived time.Time
+}
+
+// orderByTime feeds out with the payloads received by members sorted
+// by ts. Payloads are released as soon as a payload at least window
+// younger was seen or after they have waited for window. out is
+// closed when all members received EOS.
+func orderByTime(out chan<- interface{}, ts func(interface{}) time.Time, window time.Duration, members ...*Member) {
+	in := make(chan interface{})
+	for _, m := range members {
+		go func(m *Member) {
+			for {
+				val := m.Recv()
+				in <- val
+				if val == EOS {
+					return
+				}
+			}
+		}(m)
+	}
+	ticker := time.NewTicker(tick(window / 2))
+	defer ticker.Stop()
+	var (
+		pending PriorityQueue
+		latest  time.Time
+		ended   int
+	)
+	release := func(watermark, now time.Time) {
+		for pending.Len() > 0 {
+			top := pending[0].value.(*timedPayload)
+			if ts(top.val).After(watermark) && now.Sub(top.arrived) < window {
+				return
+			}
+			heap.Pop(&pending)
+			out <- top.val
+		}
+	}
+	for {
+		select {
+		case val := <-in:
+			if val == EOS {
+				if ended++; ended == len(members) {
+					for pending.Len() > 0 {
+						out <- heap.Pop(&pending).(*Item).value.(*timedPayload).val
+					}
+					close(out)
+					return
+				}
+				continue
+			}
+			stamp := ts(val)
+			now := time.Now()
+			heap.Push(&pending, &Item{
+				priority: int(stamp.UnixNano()),
+				value:    &timedPayload{val: val, arrived: now},
+			})
+			if stamp.After(latest) {
+				latest = stamp
+			}
+			release(latest.Add(-window), now)
+		case now := <-ticker.C:
+			release(latest.Add(-window), now)
+		}
+	}
+}
+
+// Violation is a kind of ordering violation found by VerifyOrder.
+type Violation int
+
+const (
+	// Gap means sequence numbers were skipped.
+	Gap Violation = iota
+	// Duplicate means a sequence number was seen before.
+	Duplicate
+	// Reorder means a skipped sequence number arrived late.
+	Reorder
+)
+
+// OrderViolation describes a sequence number breaking the order
+// expected by VerifyOrder.
+type OrderViolation struct {
+	Kind     Violation
+	Seq      int
+	Expected int
+}
+
+// VerifyOrder passes everything member m receives to the returned
+// channel and checks on the way that the sequence numbers reported by
+// seq grow one by one. Every violation is passed to report. Values
+// without a sequence number (seq returns false) are not checked. The
+// channel is closed once m receives EOS.
+func VerifyOrder(m *Member, seq func(interface{}) (int, bool), report func(OrderViolation)) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		var (
+			expected int
+			started  bool
+			missing  = make(map[int]bool)
+		)
+		for {
+			val := m.Recv()
+			if val == EOS {
+				return
+			}
+			if s, ok := seq(val); ok {
+				switch {
+				case !started || s == expected:
+					started = true
+					expected = s + 1
+				case s > expected:
+					report(OrderViolation{Kind: Gap, Seq: s, Expected: expected})
+					for skipped := expected; skipped < s; skipped++ {
+						missing[skipped] = true
+					}
+					expected = s + 1
+				case missing[s]:
+					report(OrderViolation{Kind: Reorder, Seq: s, Expected: expected})
+					delete(missing, s)
+				default:
+					report(OrderViolation{Kind: Duplicate, Seq: s, Expected: expected})
+				}
+			}
+			out <- val
+		}
+	}()
+	return out
+}
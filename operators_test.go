@@ -0,0 +1,232 @@
+package bcast
+
+import (
+	"testing"
+	"time"
+)
+
+// Create new broadcast group.
+// Tee it into two derived groups, one of them dropping.
+// Check that both derived groups receive the message.
+func TestTee(t *testing.T) {
+	group := NewGroup()
+	outs := Tee(group, 2, TeePolicy{}, TeePolicy{Buffer: 1, Drop: true})
+	if len(outs) != 2 {
+		t.Fatal("incorrect number of derived groups")
+	}
+	member1 := outs[0].Join()
+	member2 := outs[1].Join()
+	go group.Broadcast(0)
+
+	group.Send("tee message")
+	for _, member := range []*Member{member1, member2} {
+		select {
+		case val := <-member.Read:
+			if val != "tee message" {
+				t.Fatal("incorrect message received")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("message was not teed")
+		}
+	}
+}
+
+// Create source and destination groups.
+// Count and sum the messages of one window.
+func TestWindow(t *testing.T) {
+	src := NewGroup()
+	dst := NewGroup()
+	counter := dst.Join()
+	Window(src.Join(), 100*time.Millisecond, Count, dst)
+	go src.Broadcast(0)
+	go dst.Broadcast(0)
+
+	for i := 1; i <= 3; i++ {
+		src.Send(i)
+	}
+	if val := counter.Recv(); val != 3 {
+		t.Fatalf("incorrect count %v", val)
+	}
+	if val := Sum([]interface{}{1, 2.5, "x"}); val != 3.5 {
+		t.Fatalf("incorrect sum %v", val)
+	}
+}
+
+// Create new broadcast groups and window an idle member.
+// Check that the empty windows send nothing.
+func TestWindowIdle(t *testing.T) {
+	src := NewGroup()
+	dst := NewGroup()
+	counter := dst.Join()
+	Window(src.Join(), 10*time.Millisecond, Count, dst)
+	go src.Broadcast(0)
+	go dst.Broadcast(0)
+
+	if val, err := counter.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("empty window sent %v", val)
+	}
+	src.Send("late")
+	if val, err := counter.RecvTimeout(time.Second); val != 1 {
+		t.Fatalf("incorrect count %v (%v)", val, err)
+	}
+}
+
+// Create new broadcast group.
+// Send a burst of messages to debounced and throttled members.
+func TestDebounceAndThrottle(t *testing.T) {
+	group := NewGroup()
+	debounced := Debounce(group.Join(), 50*time.Millisecond)
+	throttled := Throttle(group.Join(), 50*time.Millisecond)
+	go group.Broadcast(0)
+
+	go func() {
+		for i := 1; i <= 5; i++ {
+			group.Send(i)
+		}
+	}()
+	if val := <-throttled; val != 1 {
+		t.Fatalf("throttle must pass the first value, got %v", val)
+	}
+	if val := <-throttled; val != 5 {
+		t.Fatalf("throttle must deliver the latest value, got %v", val)
+	}
+	if val := <-debounced; val != 5 {
+		t.Fatalf("debounce must deliver the last value, got %v", val)
+	}
+}
+
+// Create two broadcast groups.
+// Send out of order timestamps to both and merge them.
+func TestMergeOrdered(t *testing.T) {
+	a := NewGroup()
+	b := NewGroup()
+	base := time.Now()
+	ts := func(val interface{}) time.Time {
+		return base.Add(time.Duration(val.(int)) * time.Millisecond)
+	}
+	merged := MergeOrdered(a, b, ts, 100*time.Millisecond)
+	go a.Broadcast(0)
+	go b.Broadcast(0)
+
+	a.Send(3)
+	b.Send(1)
+	a.Send(4)
+	b.Send(2)
+	for expected := 1; expected <= 4; expected++ {
+		if val := <-merged; val != expected {
+			t.Fatalf("expected %d, got %v", expected, val)
+		}
+	}
+}
+
+// Create new broadcast group.
+// Check that the debounced channel is flushed and closed on EOS.
+func TestOperatorsStopOnEOS(t *testing.T) {
+	group := NewGroup()
+	debounced := Debounce(group.Join(), time.Hour)
+	go group.Broadcast(0)
+
+	group.Send("pending")
+	group.CloseSend()
+	if val := <-debounced; val != "pending" {
+		t.Fatalf("pending value must be flushed, got %v", val)
+	}
+	if _, ok := <-debounced; ok {
+		t.Fatal("debounced channel must be closed")
+	}
+}
+
+// Create new broadcast group.
+// Send sequence numbers out of order and check the violations.
+func TestVerifyOrder(t *testing.T) {
+	group := NewGroup()
+	var violations []OrderViolation
+	seq := func(val interface{}) (int, bool) {
+		s, ok := val.(int)
+		return s, ok
+	}
+	verified := VerifyOrder(group.Join(), seq, func(v OrderViolation) {
+		violations = append(violations, v)
+	})
+	go group.Broadcast(0)
+
+	go func() {
+		for _, s := range []int{1, 3, 2, 3} {
+			group.Send(s)
+		}
+		group.CloseSend()
+	}()
+	for range verified {
+	}
+	expected := []Violation{Gap, Reorder, Duplicate}
+	if len(violations) != len(expected) {
+		t.Fatalf("unexpected violations %v", violations)
+	}
+	for i, kind := range expected {
+		if violations[i].Kind != kind {
+			t.Fatalf("unexpected violations %v", violations)
+		}
+	}
+}
+
+// Create new broadcast group.
+// Send payloads with out of order event times to one member.
+func TestEventTimeOrder(t *testing.T) {
+	group := NewGroup()
+	base := time.Now()
+	ts := func(val interface{}) time.Time {
+		return base.Add(time.Duration(val.(int)) * 10 * time.Millisecond)
+	}
+	ordered := EventTimeOrder(group.Join(), ts, 100*time.Millisecond)
+	go group.Broadcast(0)
+
+	go func() {
+		for _, val := range []int{2, 3, 1} {
+			group.Send(val)
+		}
+		group.CloseSend()
+	}()
+	expected := 1
+	for val := range ordered {
+		if val != expected {
+			t.Fatalf("expected %d, got %v", expected, val)
+		}
+		expected++
+	}
+}
+
+// Create two broadcast groups.
+// Use the time-based operators with zero windows and check that the
+// merged members leave once both groups ended.
+func TestOperatorsZeroWindow(t *testing.T) {
+	a := NewGroup()
+	b := NewGroup()
+	ts := func(val interface{}) time.Time { return time.Unix(int64(val.(int)), 0) }
+	merged := MergeOrdered(a, b, ts, 0)
+	ordered := EventTimeOrder(a.Join(), ts, 0)
+	windows := NewGroup()
+	counts := windows.Join()
+	go windows.Broadcast(0)
+	Window(b.Join(), 0, Count, windows)
+	go a.Broadcast(0)
+	go b.Broadcast(0)
+
+	a.Send(1)
+	b.Send(2)
+	a.CloseSend()
+	b.CloseSend()
+	for range merged {
+	}
+	for range ordered {
+	}
+	if _, err := counts.RecvTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for a.MemberCount() != 1 || b.MemberCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("merged members must leave, %d and %d left", a.MemberCount(), b.MemberCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,86 @@
+package bcast
+
+import (
+	"errors"
+	"time"
+)
+
+// Option configures a Group created by NewGroup.
+type Option func(*Group)
+
+// NilPolicy defines how a group treats nil payloads, which members may
+// otherwise confuse with the nil they get for control messages.
+type NilPolicy int
+
+const (
+	// NilAllow delivers nil payloads as is. It is the default.
+	NilAllow NilPolicy = iota
+	// NilReject makes sending a nil payload panic with
+	// ErrNilPayload, except for TrySend, SendContext and SendSync,
+	// which report it through their result.
+	NilReject
+	// NilWrap delivers every payload wrapped in an *Envelope.
+	// Payloads which are envelopes already are delivered as is.
+	NilWrap
+)
+
+// ErrNilPayload is the panic value of sending nil to a group with the
+// NilReject policy, and the error SendContext returns for it.
+var ErrNilPayload = errors.New("bcast: nil payload")
+
+// WithHibernation makes members park their listener goroutine after
+// being idle for d and start it again on the next message, cutting the
+// steady-state cost of large groups of mostly idle members.
+func WithHibernation(d time.Duration) Option {
+	return func(g *Group) {
+		g.hibernate = d
+	}
+}
+
+// WithRelays spreads the fan-out of groups wider than relays members
+// over relays goroutines, each delivering to its own share of the
+// members, so the broadcast loop does not deal with every member of a
+// very large group itself.
+func WithRelays(relays int) Option {
+	return func(g *Group) {
+		g.relays = relays
+	}
+}
+
+// WithQueueCapacity preallocates room for n held back messages in the
+// queue of every member joining the group, sparing the queue growth
+// during the first bursts. Queues still grow beyond n when needed and
+// shrink again after a burst.
+func WithQueueCapacity(n int) Option {
+	return func(g *Group) {
+		g.queueCap = n
+	}
+}
+
+// WithRate limits the values sent to the group to rate per second
+// with bursts of up to burst values. Unlike a Limiter passed to
+// WithLimiter, the rate may be changed later with Reconfigure.
+func WithRate(rate float64, burst int) Option {
+	return func(g *Group) {
+		g.rate.Store(NewLimiter(rate, burst, nil))
+	}
+}
+
+// WithClockCheck turns on a debug check of the member clocks. A member
+// gets stuck when it waits for a clock older than every message it
+// holds back and no message in flight can fill the gap, which happens
+// only if the clocks went wrong. The check reports such a member to
+// logf once per stall instead of letting it stall silently; Resync
+// gets it going again.
+func WithClockCheck(logf func(format string, args ...interface{})) Option {
+	return func(g *Group) {
+		g.clockCheck = logf
+	}
+}
+
+// WithNilPolicy sets the policy for nil payloads.
+func WithNilPolicy(policy NilPolicy) Option {
+	return func(g *Group) {
+		g.nilPolicy.Store(int32(policy))
+	}
+}
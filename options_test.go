@@ -0,0 +1,167 @@
+package bcast
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Create new broadcast group rejecting nil payloads.
+// Check that sending nil panics.
+func TestNilReject(t *testing.T) {
+	group := NewGroup(WithNilPolicy(NilReject))
+	defer func() {
+		if recover() != ErrNilPayload {
+			t.Fatal("sending nil must panic with ErrNilPayload")
+		}
+	}()
+	group.Send(nil)
+}
+
+// Create new broadcast group rejecting nil payloads.
+// Check that the sends reporting failures return them instead of panicking.
+func TestNilRejectResult(t *testing.T) {
+	group := NewGroup(WithNilPolicy(NilReject))
+	member := group.Join()
+	go group.Broadcast(0)
+
+	if group.TrySend(nil) {
+		t.Fatal("TrySend must refuse nil")
+	}
+	if err := group.SendContext(context.Background(), nil); err != ErrNilPayload {
+		t.Fatalf("expected ErrNilPayload, got %v", err)
+	}
+	if n := group.SendSync(nil, time.Second); n != 0 {
+		t.Fatalf("nil reached %d members", n)
+	}
+	if val, err := member.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("rejected nil delivered as %v", val)
+	}
+}
+
+// Create new broadcast group wrapping payloads.
+// Check that a nil payload arrives in an envelope.
+func TestNilWrap(t *testing.T) {
+	group := NewGroup(WithNilPolicy(NilWrap))
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go group.Send(nil)
+	envelope, ok := member.Recv().(*Envelope)
+	if !ok || envelope.Payload != nil {
+		t.Fatal("payload must be delivered in an envelope")
+	}
+}
+
+// Create new broadcast group wrapping payloads and send an envelope.
+// Check that the envelope is not wrapped again.
+func TestNilWrapEnvelope(t *testing.T) {
+	group := NewGroup(WithNilPolicy(NilWrap))
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go group.SendEnvelope(&Envelope{Headers: map[string]string{"id": "1"}, Payload: "sealed"})
+	envelope, ok := member.Recv().(*Envelope)
+	if !ok || envelope.Payload != "sealed" || envelope.Headers["id"] != "1" {
+		t.Fatalf("envelope must be delivered as is, got %+v", envelope)
+	}
+}
+
+// Create new broadcast group with hibernating members.
+// Let a member fall asleep and wake it up with a message.
+func TestHibernation(t *testing.T) {
+	group := NewGroup(WithHibernation(10 * time.Millisecond))
+	member := group.Join()
+	go group.Broadcast(0)
+
+	time.Sleep(50 * time.Millisecond)
+	member.listenLock.Lock()
+	listening := member.listening
+	member.listenLock.Unlock()
+	if listening {
+		t.Fatal("idle member must hibernate")
+	}
+	go group.Send("wake up")
+	if val := member.Recv(); val != "wake up" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
+
+// Create new broadcast group with relays.
+// Join more members than relays and broadcast to all of them.
+func TestRelays(t *testing.T) {
+	group := NewGroup(WithRelays(4))
+	var members []*Member
+	for i := 0; i < 50; i++ {
+		members = append(members, group.Join())
+	}
+	go group.Broadcast(0)
+
+	go group.Send("relayed message")
+	for _, member := range members {
+		if val := member.Recv(); val != "relayed message" {
+			t.Fatalf("unexpected value %v", val)
+		}
+	}
+}
+
+// Create new broadcast group with relays and broadcast twice, joining a
+// member in between. Check that the shards are kept between batches and
+// rebuilt for the new member.
+func TestRelayShards(t *testing.T) {
+	group := NewGroup(WithRelays(4))
+	var members []*Member
+	for i := 0; i < 10; i++ {
+		members = append(members, group.Join())
+	}
+	go group.Broadcast(0)
+
+	group.memberLock.Lock()
+	first := group.relayShards()
+	again := group.relayShards()
+	group.memberLock.Unlock()
+	if len(first) != 4 || &first[0][0] != &again[0][0] {
+		t.Fatalf("shards must be kept, got %d shards", len(first))
+	}
+	members = append(members, group.Join())
+	group.memberLock.Lock()
+	rebuilt := group.relayShards()
+	group.memberLock.Unlock()
+	if n := len(rebuilt[0]) + len(rebuilt[1]) + len(rebuilt[2]) + len(rebuilt[len(rebuilt)-1]); n != 11 || len(rebuilt) != 4 {
+		t.Fatalf("unexpected shards %d of %d members", len(rebuilt), n)
+	}
+
+	go group.Send("relayed message")
+	for _, member := range members {
+		if val := member.Recv(); val != "relayed message" {
+			t.Fatalf("unexpected value %v", val)
+		}
+	}
+}
+
+// Create new broadcast group with the clock check and skew the clock of
+// the group behind the back of a member.
+func TestClockCheck(t *testing.T) {
+	reports := make(chan string, 1)
+	group := NewGroup(WithClockCheck(func(format string, args ...interface{}) {
+		select {
+		case reports <- fmt.Sprintf(format, args...):
+		default:
+		}
+	}))
+	member := group.Join()
+	group.clock.Store(100)
+	go group.Broadcast(0)
+
+	group.Send("stuck")
+	select {
+	case <-reports:
+	case <-time.After(time.Second):
+		t.Fatal("skewed clock was not reported")
+	}
+	member.Resync()
+	if val := member.Recv(); val != "stuck" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
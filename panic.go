@@ -0,0 +1,94 @@
+package bcast
+
+import (
+	"container/heap"
+	"log"
+)
+
+// PanicPolicy defines how a group handles panics of the code it runs
+// on behalf of its users: middleware, the authorizer, member filters,
+// the delivery to Read channels, audit sinks, log funcs, limiter costs,
+// Sampler callbacks, bridge transforms, Member.Handle handlers and the
+// leak callback of JoinWeak.
+type PanicPolicy int
+
+const (
+	// PanicCrash lets panics crash the program. It is the default.
+	PanicCrash PanicPolicy = iota
+	// PanicIsolateMember drops the message the panic happened on
+	// and removes the member involved from the group: the member
+	// whose filter, delivery or handler panicked, or the member which
+	// sent a message middleware, the authorizer or an audit sink
+	// panicked on. A panicking bridge transform stops the bridge.
+	PanicIsolateMember
+	// PanicRestartLoop drops the message the panic happened on and
+	// restarts the loop that panicked with the next message: the
+	// broadcast loop, the listener of a member, a bridge or Handle.
+	// No member is removed.
+	PanicRestartLoop
+)
+
+// WithPanicPolicy sets the policy for panics of the code the group
+// runs on behalf of its users. Recovered panics are logged.
+func WithPanicPolicy(policy PanicPolicy) Option {
+	return func(g *Group) {
+		g.panicPolicy = policy
+	}
+}
+
+// guard runs f and reports whether it panicked. With PanicCrash, or
+// without a group, the panic is not recovered.
+func (g *Group) guard(where string, f func()) (panicked bool) {
+	if g == nil || g.panicPolicy == PanicCrash {
+		f()
+		return false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("bcast: recovered panic in %s: %v", where, r)
+			panicked = true
+		}
+	}()
+	f()
+	return false
+}
+
+// isolate removes member from the group under PanicIsolateMember. It
+// may be called with the member lock held.
+func (g *Group) isolate(member *Member) {
+	if g.panicPolicy == PanicIsolateMember && member != nil && member != nobody {
+		go g.leave(member, false)
+	}
+}
+
+// safely runs f, a step of the listener of the member, and recovers
+// from panics in the delivery by skipping the message being delivered.
+// The listener then goes on with the queued messages unless the member
+// is isolated.
+func (m *Member) safely(f func()) {
+	for m.group.guard("delivery", f) && m.skipFailed() {
+		f = m.sendQueued
+	}
+}
+
+// skipFailed skips the message the delivery panicked on as if it was
+// dropped and reports whether the listener may go on delivering.
+func (m *Member) skipFailed() bool {
+	failed := m.delivering
+	m.delivering = nil
+	if failed == nil {
+		return false
+	}
+	if !m.resolved {
+		failed.report.resolve(false)
+	}
+	if m.messageQueue.Len() > 0 && m.messageQueue[0].value == failed {
+		releaseItem(heap.Pop(&m.messageQueue).(*Item))
+	}
+	m.clock = failed.clock + 1
+	if m.group.panicPolicy == PanicIsolateMember {
+		m.group.isolate(m)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package bcast
+
+import (
+	"testing"
+	"time"
+)
+
+// Create new broadcast group isolating panicking members.
+// Check that a member with a panicking filter is removed.
+func TestPanicIsolateMember(t *testing.T) {
+	group := NewGroup(WithPanicPolicy(PanicIsolateMember))
+	group.JoinFiltered(func(interface{}) bool { panic("broken filter") })
+	member := group.Join()
+	go group.Broadcast(0)
+
+	group.Send("survived")
+	if val := member.Recv(); val != "survived" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	deadline := time.Now().Add(time.Second)
+	for group.MemberCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("panicking member was not removed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Create new broadcast group recovering panics.
+// Check that a message a middleware panicked on is dropped.
+func TestPanicRestartLoop(t *testing.T) {
+	group := NewGroup(WithPanicPolicy(PanicRestartLoop))
+	member := group.Join()
+	group.Use(func(message Message) (Message, bool) {
+		if message.Payload() == "poison" {
+			panic("broken middleware")
+		}
+		return message, true
+	})
+	go group.Broadcast(0)
+
+	go func() {
+		group.Send("poison")
+		group.Send("healthy")
+	}()
+	if val := member.Recv(); val != "healthy" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
+
+// Create new broadcast groups with a member whose Read channel the
+// application closed. Check that the delivery panic is recovered and
+// that only PanicIsolateMember removes the member.
+func TestPanicDelivery(t *testing.T) {
+	for _, policy := range []PanicPolicy{PanicIsolateMember, PanicRestartLoop} {
+		group := NewGroup(WithPanicPolicy(policy))
+		broken := make(chan interface{})
+		group.Add(broken)
+		close(broken)
+		member := group.Join()
+		go group.Broadcast(0)
+
+		go func() {
+			for i := 0; i < 3; i++ {
+				group.Send(i)
+			}
+		}()
+		for i := 0; i < 3; i++ {
+			if val, err := member.RecvTimeout(time.Second); val != i {
+				t.Fatalf("policy %d: unexpected value %v (%v)", policy, val, err)
+			}
+		}
+		want := 2
+		if policy == PanicIsolateMember {
+			want = 1
+		}
+		deadline := time.Now().Add(time.Second)
+		for group.MemberCount() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("policy %d: %d members left, want %d", policy, group.MemberCount(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		group.Close()
+	}
+}
+
+// Create new broadcast groups with a middleware panicking on the
+// payloads of one member. Check that only PanicIsolateMember removes
+// the sender.
+func TestPanicMiddlewareSender(t *testing.T) {
+	for _, policy := range []PanicPolicy{PanicIsolateMember, PanicRestartLoop} {
+		group := NewGroup(WithPanicPolicy(policy))
+		sender, member := group.Join(), group.Join()
+		group.Use(func(message Message) (Message, bool) {
+			if message.Sender() == sender {
+				panic("broken middleware")
+			}
+			return message, true
+		})
+		go group.Broadcast(0)
+
+		go func() {
+			sender.Send("poison")
+			group.Send("healthy")
+		}()
+		if val, err := member.RecvTimeout(time.Second); val != "healthy" {
+			t.Fatalf("policy %d: unexpected value %v (%v)", policy, val, err)
+		}
+		want := 2
+		if policy == PanicIsolateMember {
+			want = 1
+		}
+		deadline := time.Now().Add(time.Second)
+		for group.MemberCount() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("policy %d: %d members left, want %d", policy, group.MemberCount(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		group.Close()
+	}
+}
+
+// Create new broadcast group recovering panics of user callbacks.
+// Check that a panicking sampler and handler don't crash the program.
+func TestPanicCallbacks(t *testing.T) {
+	group := NewGroup(WithPanicPolicy(PanicRestartLoop))
+	sampled := make(chan bool, 1)
+	group.Use(Sampler(1, func(interface{}) {
+		sampled <- true
+		panic("broken sampler")
+	}))
+	member := group.Join()
+	go group.Broadcast(0)
+
+	go func() {
+		group.Send("value")
+		group.CloseSend()
+	}()
+	q := NewQuarantine()
+	member.Handle(func(interface{}) error { panic("broken handler") }, 2, q)
+	<-sampled
+	if entries := q.Entries(); len(entries) != 1 || entries[0].Err != errHandlerPanicked {
+		t.Fatalf("panicking handler must quarantine its value, got %v", entries)
+	}
+}
@@ -2,8 +2,19 @@ package bcast
 
 import (
 	"container/heap"
+	"sync"
 )
 
+// shrinkCapacity is the capacity above which a queue filled to no more
+// than a quarter of its backing array gives the unused memory back.
+const shrinkCapacity = 64
+
+// itemPool recycles the Items of member queues, which come and go with
+// every message held back.
+var itemPool = sync.Pool{
+	New: func() interface{} { return new(Item) },
+}
+
 // An Item is something we manage in a priority queue.
 type Item struct {
 	value    interface{}
@@ -15,11 +26,31 @@ type Item struct {
 // A PriorityQueue implements heap.Interface and holds Items.
 type PriorityQueue []*Item
 
+// newItem takes an Item from the pool.
+func newItem(value interface{}, priority int) *Item {
+	item := itemPool.Get().(*Item)
+	item.value = value
+	item.priority = priority
+	return item
+}
+
+// releaseItem puts an Item popped from a queue back into the pool.
+func releaseItem(item *Item) {
+	*item = Item{}
+	itemPool.Put(item)
+}
+
 func (pq PriorityQueue) Len() int { return len(pq) }
 
 func (pq PriorityQueue) Less(i, j int) bool {
 	// We want Pop to give us the lowest priority so we use less than here.
-	return pq[i].priority < pq[j].priority
+	return before(pq[i].priority, pq[j].priority)
+}
+
+// before compares clocks in a way that survives their wraparound: a
+// is before b when it is less than half of the int range behind.
+func before(a, b int) bool {
+	return a-b < 0
 }
 
 func (pq PriorityQueue) Swap(i, j int) {
@@ -40,10 +71,25 @@ func (pq *PriorityQueue) Pop() interface{} {
 	n := len(old)
 	item := old[n-1]
 	item.index = -1 // for safety
+	old[n-1] = nil  // do not keep the item alive
 	*pq = old[0 : n-1]
 	return item
 }
 
+// shrink moves the items to a smaller backing array once a burst is
+// over and most of the current one is unused. The capacity never drops
+// below shrinkCapacity nor below floor, the capacity the queue was
+// created with.
+func (pq *PriorityQueue) shrink(floor int) {
+	floor = max(floor, shrinkCapacity)
+	if cap(*pq) <= floor || len(*pq) > cap(*pq)/4 {
+		return
+	}
+	shrunk := make(PriorityQueue, len(*pq), max(cap(*pq)/2, floor))
+	copy(shrunk, *pq)
+	*pq = shrunk
+}
+
 // update modifies the priority and value of an Item in the queue.
 func (pq *PriorityQueue) update(item *Item, value string, priority int) {
 	item.value = value
@@ -0,0 +1,118 @@
+package bcast
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Quarantine keeps the values a handler run by Member.Handle failed on
+// repeatedly, so they don't block the member forever.
+type Quarantine struct {
+	lock     sync.Mutex
+	entries  []Quarantined
+	requeued []interface{}
+	wake     chan bool
+}
+
+// Quarantined is a value put in quarantine with its failure details.
+type Quarantined struct {
+	Value    interface{}
+	Err      error // the error of the last attempt
+	Attempts int
+	Time     time.Time
+}
+
+// NewQuarantine creates an empty quarantine.
+func NewQuarantine() *Quarantine {
+	return &Quarantine{wake: make(chan bool, 1)}
+}
+
+// Entries returns the values currently in quarantine, oldest first.
+func (q *Quarantine) Entries() []Quarantined {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	res := make([]Quarantined, len(q.entries))
+	copy(res, q.entries)
+	return res
+}
+
+// Requeue takes the i-th entry out of quarantine and passes its value
+// to the handler again. It reports false for an unknown entry.
+func (q *Quarantine) Requeue(i int) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if i < 0 || i >= len(q.entries) {
+		return false
+	}
+	q.requeued = append(q.requeued, q.entries[i].Value)
+	q.entries = append(q.entries[:i], q.entries[i+1:]...)
+	select {
+	case q.wake <- true:
+	default:
+	}
+	return true
+}
+
+func (q *Quarantine) add(val interface{}, err error, attempts int) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.entries = append(q.entries, Quarantined{Value: val, Err: err, Attempts: attempts, Time: time.Now()})
+}
+
+func (q *Quarantine) next() (interface{}, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.requeued) == 0 {
+		return nil, false
+	}
+	val := q.requeued[0]
+	q.requeued = q.requeued[1:]
+	return val, true
+}
+
+// errHandlerPanicked is the failure recorded for a handler which
+// panicked under PanicRestartLoop.
+var errHandlerPanicked = errors.New("bcast: handler panicked")
+
+// Handle calls handler for every value the member receives, until it
+// receives EOS. A value the handler fails on attempts times in a row
+// is put in quarantine q and the member goes on with the next one.
+// Every value is tried at least once, whatever attempts is. Values
+// requeued from q are handled again. Panics of handler follow
+// the panic policy of the group: a recovered panic counts as a failed
+// attempt, and an isolated member leaves the group and Handle returns.
+func (m *Member) Handle(handler func(interface{}) error, attempts int, q *Quarantine) {
+	attempts = max(attempts, 1)
+	for {
+		val, ok := q.next()
+		if !ok {
+			if val, ok = m.unstash(); !ok {
+				select {
+				case val = <-m.Read:
+				case <-q.wake:
+					continue
+				}
+			}
+		}
+		if val == EOS {
+			return
+		}
+		var err error
+		for attempt := 1; attempt <= attempts; attempt++ {
+			if m.group.guard("handler", func() { err = handler(val) }) {
+				if m.group.panicPolicy == PanicIsolateMember {
+					m.group.leave(m, false)
+					return
+				}
+				err = errHandlerPanicked
+			}
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			q.add(val, err, attempts)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package bcast
+
+import (
+	"errors"
+	"testing"
+)
+
+// Create new broadcast group.
+// Handle messages with a handler failing on one of them.
+func TestQuarantine(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	poisoned := true
+	handled := make(chan interface{}, 3)
+	handler := func(val interface{}) error {
+		if val == "poison" && poisoned {
+			return errors.New("cannot handle")
+		}
+		handled <- val
+		return nil
+	}
+	q := NewQuarantine()
+	done := make(chan bool)
+	go func() {
+		member.Handle(handler, 3, q)
+		done <- true
+	}()
+
+	group.Send("poison")
+	group.Send("healthy")
+	if val := <-handled; val != "healthy" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	entries := q.Entries()
+	if len(entries) != 1 || entries[0].Value != "poison" || entries[0].Attempts != 3 {
+		t.Fatalf("unexpected quarantine %v", entries)
+	}
+
+	poisoned = false
+	q.Requeue(0)
+	if val := <-handled; val != "poison" {
+		t.Fatalf("requeued value must be handled, got %v", val)
+	}
+	group.CloseSend()
+	<-done
+}
+
+// Create new broadcast group.
+// Handle messages without attempts and check that every one is tried once.
+func TestQuarantineNoAttempts(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	handled := make(chan interface{}, 2)
+	handler := func(val interface{}) error {
+		handled <- val
+		if val == "poison" {
+			return errors.New("cannot handle")
+		}
+		return nil
+	}
+	q := NewQuarantine()
+	done := make(chan bool)
+	go func() {
+		member.Handle(handler, 0, q)
+		done <- true
+	}()
+
+	group.Send("poison")
+	group.Send("healthy")
+	group.CloseSend()
+	<-done
+	if first, second := <-handled, <-handled; first != "poison" || second != "healthy" {
+		t.Fatalf("unexpected values %v, %v", first, second)
+	}
+	entries := q.Entries()
+	if len(entries) != 1 || entries[0].Value != "poison" || entries[0].Attempts != 1 {
+		t.Fatalf("unexpected quarantine %v", entries)
+	}
+}
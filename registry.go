@@ -0,0 +1,129 @@
+package bcast
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TxnHeader is the envelope header SendAll puts the transaction ID in.
+const TxnHeader = "bcast-txn"
+
+// Registry creates groups by name on first use, so independent
+// packages of a program can meet on the same group without passing it
+// around.
+type Registry struct {
+	lock   sync.Mutex
+	opts   []Option
+	groups map[string]*Group
+}
+
+// NewRegistry returns a registry creating its groups with opts.
+func NewRegistry(opts ...Option) *Registry {
+	return &Registry{opts: opts, groups: make(map[string]*Group)}
+}
+
+// DefaultRegistry is the registry used by GroupNamed.
+var DefaultRegistry = NewRegistry()
+
+// GroupNamed returns the group with the given name from
+// DefaultRegistry.
+func GroupNamed(name string) *Group {
+	return DefaultRegistry.Group(name)
+}
+
+// Group returns the group with the given name, creating it and
+// starting its broadcast loop when it does not exist yet.
+func (r *Registry) Group(name string) *Group {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	g, ok := r.groups[name]
+	if !ok {
+		g = NewGroup(r.opts...)
+		go g.Broadcast(0)
+		r.groups[name] = g
+	}
+	return g
+}
+
+// Names returns the names of the groups in the registry, sorted.
+func (r *Registry) Names() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	names := make([]string, 0, len(r.groups))
+	for name := range r.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Remove stops the broadcast loop of the group with the given name and
+// of its children and removes it from the registry. The next Group
+// call with the name creates a new group.
+func (r *Registry) Remove(name string) {
+	r.lock.Lock()
+	g, ok := r.groups[name]
+	delete(r.groups, name)
+	r.lock.Unlock()
+	if ok {
+		g.Close()
+	}
+}
+
+// SendAll sends each value of vals in an *Envelope to the group of the
+// registry named by its key. The envelopes share the TxnHeader header
+// set to txnID, so consumers can relate them. The groups are frozen
+// until SendAll returns, so no member sees one of the values before
+// every group took its own or SendAll gave up.
+//
+// Only the names are checked up front: when a group does not exist an
+// error is returned and nothing is sent. A group whose loop does not
+// take its value before ctx is done fails the transaction with an error
+// wrapping ctx.Err(), but the values already handed to other groups are
+// delivered all the same.
+func (r *Registry) SendAll(ctx context.Context, txnID string, vals map[string]interface{}) error {
+	names := make([]string, 0, len(vals))
+	for name := range vals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	groups := make([]*Group, len(names))
+	r.lock.Lock()
+	for i, name := range names {
+		g, ok := r.groups[name]
+		if !ok {
+			r.lock.Unlock()
+			return fmt.Errorf("bcast: unknown group %q", name)
+		}
+		groups[i] = g
+	}
+	r.lock.Unlock()
+
+	// Groups frozen by somebody else stay frozen.
+	var frozen []*Group
+	for _, g := range groups {
+		if g.freeze() {
+			frozen = append(frozen, g)
+		}
+	}
+	defer func() {
+		for _, g := range frozen {
+			g.Unfreeze()
+		}
+	}()
+	now := time.Now()
+	for i, g := range groups {
+		env := &Envelope{
+			Payload: vals[names[i]],
+			Headers: map[string]string{TxnHeader: txnID},
+			Time:    now,
+		}
+		if err := g.SendContext(ctx, env); err != nil {
+			return fmt.Errorf("bcast: sending to group %q: %w", names[i], err)
+		}
+	}
+	return nil
+}
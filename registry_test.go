@@ -0,0 +1,77 @@
+package bcast
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Create a registry and meet on a named group from two places.
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	member := registry.Group("events").Join()
+	registry.Group("events").Send("rendezvous")
+	if val := member.Recv(); val != "rendezvous" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	if names := registry.Names(); len(names) != 1 || names[0] != "events" {
+		t.Fatalf("unexpected names %v", names)
+	}
+	registry.Remove("events")
+	if registry.Group("events").MemberCount() != 0 {
+		t.Fatal("removed group must be replaced by a new one")
+	}
+	if GroupNamed("events") != GroupNamed("events") {
+		t.Fatal("default registry must return the same group")
+	}
+}
+
+// Create a registry with two groups and send a transaction to both.
+// Check that both get their value with the transaction ID and that an
+// unknown group fails the whole transaction.
+func TestRegistrySendAll(t *testing.T) {
+	registry := NewRegistry()
+	rooms, audit := registry.Group("rooms").Join(), registry.Group("audit").Join()
+	registry.Group("audit").Freeze()
+
+	err := registry.SendAll(context.Background(), "txn-1", map[string]interface{}{"rooms": "joined", "audit": "user joined"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := rooms.RecvEnvelope()
+	if err != nil || env.Payload != "joined" || env.Headers[TxnHeader] != "txn-1" {
+		t.Fatalf("unexpected envelope %+v (%v)", env, err)
+	}
+	if val, ok := audit.TryRecv(); ok {
+		t.Fatalf("group frozen before the transaction got %v", val)
+	}
+	registry.Group("audit").Unfreeze()
+	if env, err = audit.RecvEnvelope(); err != nil || env.Payload != "user joined" || env.Headers[TxnHeader] != "txn-1" {
+		t.Fatalf("unexpected envelope %+v (%v)", env, err)
+	}
+
+	if err := registry.SendAll(context.Background(), "txn-2", map[string]interface{}{"rooms": "left", "missing": "x"}); err == nil {
+		t.Fatal("transaction with an unknown group must fail")
+	}
+	if val, err := rooms.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("failed transaction delivered %v", val)
+	}
+}
+
+// Create a registry with a group whose loop was stopped directly and
+// send a transaction to it. Check that SendAll gives up with ctx and
+// does not hold the registry meanwhile.
+func TestRegistrySendAllStopped(t *testing.T) {
+	registry := NewRegistry()
+	registry.Group("stopped").Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error)
+	go func() { done <- registry.SendAll(ctx, "txn-1", map[string]interface{}{"stopped": "lost"}) }()
+	registry.Group("other")
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package bcast
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ConfigUpdate is what the subscribers of a ConfigBroadcaster receive.
+type ConfigUpdate struct {
+	// Config is the whole configuration after the update.
+	Config map[string]interface{}
+	// Changed holds the keys added or changed by the update with
+	// their new values. The first update a subscriber gets holds
+	// every key.
+	Changed map[string]interface{}
+	// Removed lists the keys removed by the update, sorted.
+	Removed []string
+}
+
+// ConfigBroadcaster retains the latest version of a configuration and
+// broadcasts what changes when it is updated. A new subscriber first
+// gets the configuration it joins with and then every later update, so
+// it never misses a reload nor sees one twice.
+type ConfigBroadcaster struct {
+	group   *Group
+	lock    sync.Mutex
+	current map[string]interface{}
+}
+
+// NewConfigBroadcaster returns a broadcaster retaining initial, with a
+// group created with opts and broadcasting already.
+func NewConfigBroadcaster(initial map[string]interface{}, opts ...Option) *ConfigBroadcaster {
+	c := &ConfigBroadcaster{group: NewGroup(opts...), current: copyConfig(initial)}
+	go c.group.Broadcast(0)
+	return c
+}
+
+// Group returns the group the updates are broadcast in.
+func (c *ConfigBroadcaster) Group() *Group {
+	return c.group
+}
+
+// Current returns a copy of the retained configuration.
+func (c *ConfigBroadcaster) Current() map[string]interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return copyConfig(c.current)
+}
+
+// Subscribe joins a new member which receives the retained
+// configuration as a ConfigUpdate before any later update. The update
+// is queued as the member joins, ahead of whatever is broadcast next.
+func (c *ConfigBroadcaster) Subscribe() *Member {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	snapshot := c.group.message(nil, &ConfigUpdate{
+		Config:  copyConfig(c.current),
+		Changed: copyConfig(c.current),
+	})
+	return c.group.add(make(chan interface{}), func(m *Member) {
+		m.preload([]Message{snapshot})
+	})
+}
+
+// Update replaces the retained configuration with config and broadcasts
+// the difference. Values are compared with reflect.DeepEqual. Nothing
+// is broadcast when config equals the retained one. Update reports
+// whether anything changed.
+func (c *ConfigBroadcaster) Update(config map[string]interface{}) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	update := &ConfigUpdate{Config: copyConfig(config), Changed: make(map[string]interface{})}
+	for key, val := range config {
+		if old, ok := c.current[key]; !ok || !reflect.DeepEqual(old, val) {
+			update.Changed[key] = val
+		}
+	}
+	for key := range c.current {
+		if _, ok := config[key]; !ok {
+			update.Removed = append(update.Removed, key)
+		}
+	}
+	if len(update.Changed) == 0 && len(update.Removed) == 0 {
+		return false
+	}
+	sort.Strings(update.Removed)
+	c.current = copyConfig(config)
+	c.group.Send(update)
+	return true
+}
+
+// Close stops the broadcast loop of the group.
+func (c *ConfigBroadcaster) Close() {
+	c.group.Close()
+}
+
+// copyConfig returns a shallow copy of config.
+func copyConfig(config map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(config))
+	for key, val := range config {
+		res[key] = val
+	}
+	return res
+}
@@ -0,0 +1,72 @@
+package bcast
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Create new config broadcaster and update it around a subscription.
+// Check that the subscriber gets the retained config first and then
+// only the differences.
+func TestConfigBroadcaster(t *testing.T) {
+	c := NewConfigBroadcaster(map[string]interface{}{"level": "info", "port": 80})
+	defer c.Close()
+	c.Update(map[string]interface{}{"level": "debug", "port": 80})
+	member := c.Subscribe()
+
+	val, err := member.RecvTimeout(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := val.(*ConfigUpdate)
+	want := map[string]interface{}{"level": "debug", "port": 80}
+	if !reflect.DeepEqual(first.Config, want) || !reflect.DeepEqual(first.Changed, want) {
+		t.Fatalf("unexpected first update %+v", first)
+	}
+
+	if c.Update(map[string]interface{}{"level": "debug", "port": 80}) {
+		t.Fatal("unchanged config must not be broadcast")
+	}
+	go c.Update(map[string]interface{}{"level": "warn", "tls": true})
+	if val, err = member.RecvTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	diff := val.(*ConfigUpdate)
+	if !reflect.DeepEqual(diff.Changed, map[string]interface{}{"level": "warn", "tls": true}) ||
+		!reflect.DeepEqual(diff.Removed, []string{"port"}) {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	if !reflect.DeepEqual(c.Current(), diff.Config) {
+		t.Fatalf("current config %v differs from %v", c.Current(), diff.Config)
+	}
+}
+
+// Create new config broadcaster and subscribe while it is updated.
+// Check that the subscriber gets every update after its snapshot once
+// and in order.
+func TestConfigBroadcasterConcurrent(t *testing.T) {
+	c := NewConfigBroadcaster(map[string]interface{}{"n": 0})
+	defer c.Close()
+	const updates = 50
+	go func() {
+		for i := 1; i <= updates; i++ {
+			c.Update(map[string]interface{}{"n": i})
+		}
+	}()
+	time.Sleep(time.Millisecond)
+	member := c.Subscribe()
+
+	want := -1
+	for want != updates {
+		val, err := member.RecvTimeout(time.Second)
+		if err != nil {
+			t.Fatalf("waiting for %d: %v", want+1, err)
+		}
+		n := val.(*ConfigUpdate).Config["n"].(int)
+		if want >= 0 && n != want+1 {
+			t.Fatalf("got update %d after %d", n, want)
+		}
+		want = n
+	}
+}
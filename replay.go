@@ -0,0 +1,170 @@
+package bcast
+
+import (
+	"container/heap"
+	"time"
+)
+
+// WithReplay makes the group keep the last n payloads broadcast to all
+// of its members and deliver them to every new member, oldest first,
+// before anything broadcast after it joined. Droppable messages and
+// messages sent to single members, tags or topics are not kept, and
+// payloads the filter of a new member rejects are not replayed to it.
+func WithReplay(n int) Option {
+	return func(g *Group) {
+		g.replayN = max(g.replayN, n)
+	}
+}
+
+// WithLastValue makes the group remember the last payload broadcast to
+// all of its members and deliver it to every new member right away,
+// like WithReplay(1). It suits state fan-out where only the latest
+// value matters. Combined with WithReplay the larger replay wins.
+func WithLastValue() Option {
+	return func(g *Group) {
+		g.replayN = max(g.replayN, 1)
+	}
+}
+
+// WithReplayWindow makes the group keep the payloads broadcast to all
+// of its members during the last d and deliver them to every new member
+// like WithReplay. With both options a payload is kept while it is
+// among the last n and younger than d. Members may also ask for the
+// kept payloads again with ReplaySince.
+func WithReplayWindow(d time.Duration) Option {
+	return func(g *Group) {
+		g.replayWindow = d
+	}
+}
+
+// retainedMessage is a message kept for replay with the time it was
+// broadcast.
+type retainedMessage struct {
+	Message
+	at time.Time
+}
+
+// replays reports whether the group keeps messages for replay.
+func (g *Group) replays() bool {
+	return g.replayN > 0 || g.replayWindow > 0
+}
+
+// LastValue returns the last payload broadcast to all members of a
+// group created with WithLastValue, WithReplay or WithReplayWindow, and
+// false when there is none left.
+func (g *Group) LastValue() (interface{}, bool) {
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	replay := g.retained(time.Now())
+	if len(replay) == 0 {
+		return nil, false
+	}
+	return replay[len(replay)-1].payload, true
+}
+
+// ReplaySince sends the member again the kept payloads broadcast at t
+// or later, oldest first, and returns their number. Payloads the member
+// sent itself or would not have received are left out, and nothing is
+// replayed after the end of stream. The payloads are broadcast right
+// away, after the messages already broadcast but ahead of those still
+// waiting for the loop, so they may arrive interleaved with live
+// traffic. Middleware and the authorizer, which saw the payloads
+// already, are not run again.
+func (m *Member) ReplaySince(t time.Time) int {
+	g := m.group
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	if g.eos || g.frozen && holdsEOS(g.held) {
+		return 0
+	}
+	var replay []Message
+	for _, retained := range g.retained(time.Now()) {
+		message := retained.Message
+		if !retained.at.Before(t) && message.sender != m && m.wants(&message) {
+			message.to = m
+			message.replayed = true
+			replay = append(replay, message)
+		}
+	}
+	if len(replay) == 0 {
+		return 0
+	}
+	if g.frozen {
+		g.held = append(g.held, replay...)
+	} else {
+		g.fanOutLocked(replay)
+	}
+	return len(replay)
+}
+
+// holdsEOS reports whether the end of stream is among messages.
+func holdsEOS(messages []Message) bool {
+	for _, message := range messages {
+		if message.msg_type == MSG_TYPE_EOS {
+			return true
+		}
+	}
+	return false
+}
+
+// retain keeps the stamped messages which may be replayed. The caller
+// holds memberLock.
+func (g *Group) retain(stamped []Message) {
+	now := time.Now()
+	for _, message := range stamped {
+		if message.msg_type != MSG_TYPE_DATA || message.droppable || message.to != nil || message.tag != "" || message.topic != "" {
+			continue
+		}
+		message.report = nil
+		g.replay = append(g.replay, retainedMessage{Message: message, at: now})
+	}
+	g.retained(now)
+}
+
+// retained drops the messages which are no longer kept at now and
+// returns the others, oldest first. The caller holds memberLock.
+func (g *Group) retained(now time.Time) []retainedMessage {
+	n, expired := len(g.replay), 0
+	if g.replayN > 0 && n > g.replayN {
+		expired = n - g.replayN
+	}
+	if g.replayWindow > 0 {
+		for expired < n && now.Sub(g.replay[expired].at) > g.replayWindow {
+			expired++
+		}
+	}
+	// Release the payloads; the backing array is reallocated to
+	// the messages still kept once the slice grows again.
+	clear(g.replay[:expired])
+	g.replay = g.replay[expired:]
+	return g.replay
+}
+
+// replayTo queues the retained messages wanted by a member which is
+// about to start listening. The caller holds memberLock.
+func (g *Group) replayTo(member *Member) {
+	var replay []Message
+	for _, retained := range g.retained(time.Now()) {
+		if member.wants(&retained.Message) {
+			replay = append(replay, retained.Message)
+		}
+	}
+	member.preload(replay)
+}
+
+// preload queues messages for a member which is about to start
+// listening. They get the clocks right before the clock the member
+// joins with, so they are delivered ahead of live traffic.
+func (m *Member) preload(messages []Message) {
+	if len(messages) == 0 {
+		return
+	}
+	m.clock -= len(messages)
+	for i := range messages {
+		message := messages[i]
+		message.clock = m.clock + i
+		heap.Push(&m.messageQueue, newItem(&message, message.clock))
+	}
+	m.backlog.Add(int64(len(messages)))
+	m.Resync()
+}
@@ -0,0 +1,181 @@
+package bcast
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Create new broadcast group replaying the last three messages.
+// Check that a late member gets them in order before live traffic.
+func TestWithReplay(t *testing.T) {
+	group := NewGroup(WithReplay(3))
+	early := group.Join()
+	go group.Broadcast(0)
+
+	for i := 0; i < 10; i++ {
+		go group.Send(i)
+		if val, err := early.RecvTimeout(time.Second); val != i {
+			t.Fatalf("unexpected value %v (%v)", val, err)
+		}
+	}
+	go group.SendTo(early, "private")
+	early.RecvTimeout(time.Second)
+	go group.SendDroppable("droppable")
+	early.RecvTimeout(time.Second)
+
+	late := group.Join()
+	odd := group.JoinFiltered(func(val interface{}) bool {
+		n, ok := val.(int)
+		return ok && n%2 == 1
+	})
+	go group.Send(11)
+	for _, want := range []interface{}{7, 8, 9, 11} {
+		if val, err := late.RecvTimeout(time.Second); val != want {
+			t.Fatalf("expected %v, got %v (%v)", want, val, err)
+		}
+	}
+	for _, want := range []interface{}{7, 9, 11} {
+		if val, err := odd.RecvTimeout(time.Second); val != want {
+			t.Fatalf("expected %v, got %v (%v)", want, val, err)
+		}
+	}
+}
+
+// Create new broadcast group remembering its last value.
+// Check that members joining later get only the latest value.
+func TestWithLastValue(t *testing.T) {
+	group := NewGroup(WithLastValue())
+	go group.Broadcast(0)
+	if _, ok := group.LastValue(); ok {
+		t.Fatal("no value was sent yet")
+	}
+
+	group.Send("v1")
+	group.Send("v2")
+	deadline := time.Now().Add(time.Second)
+	for val, _ := group.LastValue(); val != "v2"; val, _ = group.LastValue() {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected last value %v", val)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	member := group.Join()
+	if val, err := member.RecvTimeout(time.Second); val != "v2" {
+		t.Fatalf("unexpected value %v (%v)", val, err)
+	}
+	if val, err := member.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("unexpected value %v", val)
+	}
+}
+
+// Create new broadcast group replaying the messages of a time window.
+// Check that late members get only the recent messages and that a
+// member can ask for them again.
+func TestWithReplayWindow(t *testing.T) {
+	group := NewGroup(WithReplayWindow(100 * time.Millisecond))
+	early := group.Join()
+	go group.Broadcast(0)
+
+	go group.Send("old")
+	early.RecvTimeout(time.Second)
+	time.Sleep(150 * time.Millisecond)
+	since := time.Now()
+	go func() {
+		group.Send("new")
+		group.Send("newer")
+	}()
+	for _, want := range []interface{}{"new", "newer"} {
+		if val, err := early.RecvTimeout(time.Second); val != want {
+			t.Fatalf("expected %v, got %v (%v)", want, val, err)
+		}
+	}
+
+	late := group.Join()
+	for _, want := range []interface{}{"new", "newer"} {
+		if val, err := late.RecvTimeout(time.Second); val != want {
+			t.Fatalf("expected %v, got %v (%v)", want, val, err)
+		}
+	}
+	if n := early.ReplaySince(since); n != 2 {
+		t.Fatalf("replayed %d messages, want 2", n)
+	}
+	for _, want := range []interface{}{"new", "newer"} {
+		if val, err := early.RecvTimeout(time.Second); val != want {
+			t.Fatalf("expected %v, got %v (%v)", want, val, err)
+		}
+	}
+	if val, err := late.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("replay for another member reached %v", val)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if n := early.ReplaySince(since); n != 0 {
+		t.Fatalf("replayed %d expired messages", n)
+	}
+	if _, ok := group.LastValue(); ok {
+		t.Fatal("last value must expire with the window")
+	}
+}
+
+// Create new broadcast group replaying with an authorizer and an audit.
+// Check that replays skip both and stop with the end of stream.
+func TestReplaySinceBypassesLoop(t *testing.T) {
+	var (
+		lock   sync.Mutex
+		sent   int
+		reject bool
+	)
+	group := NewGroup(
+		WithReplayWindow(time.Minute),
+		WithAudit(func(record AuditRecord) {
+			lock.Lock()
+			defer lock.Unlock()
+			if record.Event == AuditSent {
+				sent++
+			}
+		}, false),
+		WithAuthorizer(func(*Member, Message) error {
+			lock.Lock()
+			defer lock.Unlock()
+			if reject {
+				return errors.New("rejected")
+			}
+			return nil
+		}),
+	)
+	sender, reader := group.Join(), group.Join()
+	go group.Broadcast(0)
+
+	since := time.Now()
+	go sender.Send("once")
+	if val, err := reader.RecvTimeout(time.Second); val != "once" {
+		t.Fatalf("unexpected value %v (%v)", val, err)
+	}
+	lock.Lock()
+	reject = true
+	lock.Unlock()
+	if n := reader.ReplaySince(since); n != 1 {
+		t.Fatalf("replayed %d messages, want 1", n)
+	}
+	if val, err := reader.RecvTimeout(time.Second); val != "once" {
+		t.Fatalf("unexpected value %v (%v)", val, err)
+	}
+	if val, err := sender.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("sender got %v for a replay", val)
+	}
+	lock.Lock()
+	if sent != 1 {
+		t.Fatalf("audited %d sends, want 1", sent)
+	}
+	lock.Unlock()
+
+	group.CloseSend()
+	if val, _ := reader.RecvTimeout(time.Second); val != EOS {
+		t.Fatalf("expected EOS, got %v", val)
+	}
+	if n := reader.ReplaySince(since); n != 0 {
+		t.Fatalf("replayed %d messages after the end of stream", n)
+	}
+}
@@ -0,0 +1,132 @@
+package bcast
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DeliveryReport tracks the propagation of a message sent with
+// SendTracked.
+type DeliveryReport struct {
+	lock      sync.Mutex
+	delivered int
+	dropped   int
+	pending   int
+	done      chan struct{}
+}
+
+func newDeliveryReport() *DeliveryReport {
+	return &DeliveryReport{done: make(chan struct{})}
+}
+
+// SendTracked broadcasts a message to every one of a Group's members
+// and returns a report telling how many of them got it.
+func (g *Group) SendTracked(val interface{}) *DeliveryReport {
+	message := g.data(nil, val)
+	message.report = newDeliveryReport()
+	g.in <- message
+	return message.report
+}
+
+// SendSync broadcasts a message like SendTracked and waits until every
+// member got it on its Read channel or dropped it, but at most timeout,
+// which also bounds the wait for the broadcast loop to take the message.
+// It returns the number of members that got the message so far, which
+// is 0 when the nil policy rejects val.
+func (g *Group) SendSync(val interface{}, timeout time.Duration) int {
+	if g.rejects(val) {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	message := g.message(nil, val)
+	message.report = newDeliveryReport()
+	taken, err := g.reserve(ctx, val, true)
+	if err != nil {
+		return 0
+	}
+	select {
+	case g.in <- message:
+	case <-ctx.Done():
+		taken.refund()
+		return 0
+	}
+	message.report.Wait(ctx)
+	return message.report.Delivered()
+}
+
+// Delivered returns the number of members that received the message
+// so far.
+func (r *DeliveryReport) Delivered() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.delivered
+}
+
+// Dropped returns the number of members that skipped the message or
+// left the group before receiving it.
+func (r *DeliveryReport) Dropped() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.dropped
+}
+
+// Wait blocks until the message was either delivered to or dropped by
+// every member of the group at the time it was broadcast. It returns
+// ctx.Err() when ctx is done first.
+func (r *DeliveryReport) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// expect sets the number of members the message is broadcast to. Nil
+// reports of untracked messages are ignored, like in the methods
+// below.
+func (r *DeliveryReport) expect(members int) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.pending = members
+	if r.pending == 0 {
+		close(r.done)
+	}
+}
+
+// resolve records the outcome of the delivery to one member.
+func (r *DeliveryReport) resolve(delivered bool) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if delivered {
+		r.delivered++
+	} else {
+		r.dropped++
+	}
+	r.complete()
+}
+
+// skip records a member the message is not meant for, its sender.
+func (r *DeliveryReport) skip() {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.complete()
+}
+
+func (r *DeliveryReport) complete() {
+	r.pending--
+	if r.pending == 0 {
+		close(r.done)
+	}
+}
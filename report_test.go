@@ -0,0 +1,58 @@
+package bcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Create new broadcast group with a reading and a leaving member.
+// Track the propagation of a message.
+func TestSendTracked(t *testing.T) {
+	group := NewGroup()
+	reader := group.Join()
+	leaving := group.Join()
+	go group.Broadcast(0)
+
+	go reader.Recv()
+	report := group.SendTracked("tracked message")
+	leaving.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := report.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if report.Delivered() != 1 || report.Dropped() != 1 {
+		t.Fatalf("expected 1 delivered and 1 dropped, got %d and %d",
+			report.Delivered(), report.Dropped())
+	}
+}
+
+// Create new broadcast group with a reading and a stuck member.
+// Check that SendSync counts only the delivery that happened in time.
+func TestSendSync(t *testing.T) {
+	group := NewGroup()
+	reader := group.Join()
+	group.Join()
+	go group.Broadcast(0)
+	go reader.Recv()
+
+	if delivered := group.SendSync("sync", 50*time.Millisecond); delivered != 1 {
+		t.Fatalf("expected 1 delivery, got %d", delivered)
+	}
+}
+
+// Create new broadcast group without a broadcast loop.
+// Check that SendSync gives up after the timeout.
+func TestSendSyncStopped(t *testing.T) {
+	group := NewGroup()
+	group.Join()
+
+	start := time.Now()
+	if delivered := group.SendSync("sync", 50*time.Millisecond); delivered != 0 {
+		t.Fatalf("expected no delivery, got %d", delivered)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("SendSync returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,117 @@
+package bcast
+
+import (
+	"context"
+	"sync"
+)
+
+// Request is delivered to members in place of the payload of
+// Group.Request. Members answer it with Reply.
+type Request struct {
+	Payload interface{}
+	lock    sync.Mutex
+	replies []interface{}
+	done    bool
+	notify  chan struct{}
+	cancel  chan struct{}
+}
+
+func newRequest(val interface{}) *Request {
+	return &Request{Payload: val, notify: make(chan struct{}, 1), cancel: make(chan struct{})}
+}
+
+// Done returns a channel which is closed once the requester stopped
+// waiting for replies, so members may give up on the request.
+func (r *Request) Done() <-chan struct{} {
+	return r.cancel
+}
+
+// Reply sends val back to the requester. Replies arriving after the
+// requester stopped waiting are discarded.
+func (r *Request) Reply(val interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.done {
+		return
+	}
+	r.replies = append(r.replies, val)
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Request broadcasts val to every member as a *Request and gathers
+// their replies. It returns once every member that received the
+// request replied, so a member that never replies keeps it waiting
+// until ctx is done. When ctx is done first it returns the replies so
+// far together with ctx.Err().
+func (g *Group) Request(ctx context.Context, val interface{}) ([]interface{}, error) {
+	req := newRequest(val)
+	message := g.data(nil, req)
+	message.report = newDeliveryReport()
+	select {
+	case g.in <- message:
+	case <-ctx.Done():
+		req.finish()
+		return nil, ctx.Err()
+	}
+	delivered := message.report.done
+	for {
+		select {
+		case <-req.notify:
+		case <-delivered:
+			delivered = nil
+		case <-ctx.Done():
+			return req.finish(), ctx.Err()
+		}
+		if delivered == nil && req.count() >= message.report.Delivered() {
+			return req.finish(), nil
+		}
+	}
+}
+
+func (r *Request) count() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.replies)
+}
+
+// finish stops the gathering of replies and returns them.
+func (r *Request) finish() []interface{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.done {
+		r.done = true
+		close(r.cancel)
+	}
+	return r.replies
+}
+
+// Hedge sends val as one *Request to each of members only and returns
+// the first reply, so members fronting redundant backends race for the
+// answer. The request is done as soon as a reply arrives: later replies
+// are discarded and the Done channel of the request tells the other
+// members to give up. When ctx is done before any reply, Hedge returns
+// ctx.Err().
+func (g *Group) Hedge(ctx context.Context, members []*Member, val interface{}) (interface{}, error) {
+	req := newRequest(val)
+	defer req.finish()
+	for _, member := range members {
+		message := g.data(nil, req)
+		message.to = member
+		select {
+		case g.in <- message:
+		case <-req.notify:
+			return req.finish()[0], nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	select {
+	case <-req.notify:
+		return req.finish()[0], nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,81 @@
+package bcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Create new broadcast group with three replying members.
+// Gather their replies to a request.
+func TestRequest(t *testing.T) {
+	group := NewGroup()
+	for i := 0; i < 3; i++ {
+		member := group.Join()
+		go func(i int) {
+			member.Recv().(*Request).Reply(i)
+		}(i)
+	}
+	go group.Broadcast(0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	replies, err := group.Request(ctx, "ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replies) != 3 {
+		t.Fatalf("expected 3 replies, got %v", replies)
+	}
+}
+
+// Create new broadcast group with a member that never replies.
+// Check that the request gives up with the context.
+func TestRequestTimeout(t *testing.T) {
+	group := NewGroup()
+	silent := group.Join()
+	go group.Broadcast(0)
+	go silent.Recv()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := group.Request(ctx, "ping"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// Create new broadcast group with a fast, a slow and an idle member.
+// Hedge a request over the fast and the slow one and check that the
+// first reply wins and the slow member is told to give up.
+func TestHedge(t *testing.T) {
+	group := NewGroup()
+	fast, slow, idle := group.Join(), group.Join(), group.Join()
+	go group.Broadcast(0)
+	go func() {
+		fast.Recv().(*Request).Reply("fast")
+	}()
+	cancelled := make(chan bool)
+	go func() {
+		req := slow.Recv().(*Request)
+		select {
+		case <-req.Done():
+			cancelled <- true
+		case <-time.After(time.Second):
+			req.Reply("slow")
+			cancelled <- false
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	val, err := group.Hedge(ctx, []*Member{fast, slow}, "ping")
+	if err != nil || val != "fast" {
+		t.Fatalf("unexpected reply %v (%v)", val, err)
+	}
+	if !<-cancelled {
+		t.Fatal("slow member was not cancelled")
+	}
+	if val, err := idle.RecvTimeout(50 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("member outside the hedge got %v", val)
+	}
+}
@@ -0,0 +1,32 @@
+package bcast
+
+// From is delivered to members receiving senders, see
+// Member.ReceiveSenders, in place of the payload.
+type From struct {
+	// Sender is the member that sent the payload or nil when it was
+	// sent to the group directly.
+	Sender  *Member
+	Payload interface{}
+}
+
+// ID returns the number of the member, unique within its group.
+func (m *Member) ID() uint64 {
+	return m.id
+}
+
+// ReceiveSenders makes the member receive every payload as a *From
+// telling which member sent it. RecvFrom unwraps it again.
+func (m *Member) ReceiveSenders(on bool) {
+	m.withSender.Store(on)
+}
+
+// RecvFrom reads one value like Recv and returns it together with its
+// sender. The sender is known only for members receiving senders and
+// is nil otherwise, as well as for values sent to the group directly.
+func (m *Member) RecvFrom() (interface{}, *Member) {
+	val := m.Recv()
+	if from, ok := val.(*From); ok {
+		return from.Payload, from.Sender
+	}
+	return val, nil
+}
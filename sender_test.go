@@ -0,0 +1,31 @@
+package bcast
+
+import "testing"
+
+// Create new broadcast group with three members, one of them receiving
+// senders.
+func TestRecvFrom(t *testing.T) {
+	group := NewGroup()
+	member1 := group.Join()
+	member2 := group.Join()
+	receiver := group.Join()
+	receiver.ReceiveSenders(true)
+	go group.Broadcast(0)
+	if member1.ID() == member2.ID() {
+		t.Fatal("member IDs must differ")
+	}
+
+	go func() {
+		member1.Send("one")
+		member2.Send("two")
+		group.Send("group")
+	}()
+	for _, expected := range []struct {
+		val    string
+		sender *Member
+	}{{"one", member1}, {"two", member2}, {"group", nil}} {
+		if val, sender := receiver.RecvFrom(); val != expected.val || sender != expected.sender {
+			t.Fatalf("expected %v from %v, got %v from %v", expected.val, expected.sender, val, sender)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package bcast
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// Backlog returns the number of messages broadcast but not yet
+// delivered, summed over all members of the group.
+func (g *Group) Backlog() int {
+	return backlog(g.Members())
+}
+
+// backlog returns the number of messages not yet delivered to members.
+func backlog(members []*Member) int {
+	backlog := 0
+	for _, member := range members {
+		backlog += int(member.backlog.Load())
+	}
+	return backlog
+}
+
+// ended reports whether the end of stream was fanned out.
+func (g *Group) ended() bool {
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	return g.eos
+}
+
+// Shutdown stops the group gracefully: it ends the stream with
+// CloseSend, waits until every member got everything sent before and
+// then stops the broadcast loop and its children, see Child. Members with a shutdown order are
+// closed on the way, see SetShutdownOrder. When ctx is done first the
+// remaining members with an order are closed, the loop is stopped if
+// it still runs and ctx.Err() is returned. Shutdown never waits past
+// ctx, not even for a loop which has already stopped.
+func (g *Group) Shutdown(ctx context.Context) error {
+	defer g.closeChildren()
+	defer g.stop(ctx)
+	var err error
+	select {
+	case g.in <- Message{msg_type: MSG_TYPE_EOS}:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	for _, stage := range g.shutdownStages() {
+		if err == nil {
+			err = g.drain(ctx, stage.members)
+		}
+		if stage.order == 0 {
+			continue
+		}
+		for _, member := range stage.members {
+			member.Close()
+		}
+	}
+	return err
+}
+
+// stop stops the broadcast loop like Close unless ctx is done before
+// the loop takes the request.
+func (g *Group) stop(ctx context.Context) {
+	select {
+	case g.close <- true:
+		return
+	default:
+	}
+	select {
+	case g.close <- true:
+	case <-ctx.Done():
+	}
+}
+
+// SetShutdownOrder makes Shutdown close the member once it received
+// everything, after all the members with a lower order were closed and
+// all the members without an order were drained. Persistence sinks and
+// the like get a high order, so they observe everything any other
+// member got and are closed last. An order of 0, the default, leaves
+// the member open after Shutdown.
+func (m *Member) SetShutdownOrder(order int) {
+	m.shutdownOrder.Store(int64(order))
+}
+
+// shutdownStage is the members sharing one shutdown order.
+type shutdownStage struct {
+	order   int
+	members []*Member
+}
+
+// shutdownStages returns the members of the group by shutdown order,
+// starting with the stage of the members without an order, which is
+// always there.
+func (g *Group) shutdownStages() []shutdownStage {
+	stages := []shutdownStage{{order: 0}}
+	for _, member := range g.Members() {
+		order := int(member.shutdownOrder.Load())
+		i := sort.Search(len(stages), func(i int) bool { return stages[i].order >= order })
+		if i == len(stages) || stages[i].order != order {
+			stages = append(stages, shutdownStage{})
+			copy(stages[i+1:], stages[i:])
+			stages[i] = shutdownStage{order: order}
+		}
+		stages[i].members = append(stages[i].members, member)
+	}
+	return stages
+}
+
+// drain waits until the end of stream was fanned out and members got
+// everything, or until ctx is done.
+func (g *Group) drain(ctx context.Context, members []*Member) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for !g.ended() || g.relaying.Load() > 0 || backlog(members) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// ShutdownOnSignal blocks until the process receives one of sigs
+// (SIGINT and SIGTERM by default) and then shuts g down, giving its
+// members at most drain to receive what is pending. The progress is
+// reported every second to logf, the standard logger is used when logf
+// is nil. It returns the result of Shutdown.
+func ShutdownOnSignal(g *Group, drain time.Duration, logf func(format string, args ...interface{}), sigs ...os.Signal) error {
+	if logf == nil {
+		logf = log.Printf
+	}
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sigs...)
+	defer signal.Stop(signals)
+
+	sig := <-signals
+	logf("bcast: %v received, draining %d pending messages", sig, g.Backlog())
+	ctx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- g.Shutdown(ctx) }()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				logf("bcast: drain interrupted with %d messages pending: %v", g.Backlog(), err)
+			} else {
+				logf("bcast: drained")
+			}
+			return err
+		case <-ticker.C:
+			logf("bcast: draining, %d messages pending", g.Backlog())
+		}
+	}
+}
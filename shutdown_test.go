@@ -0,0 +1,103 @@
+package bcast
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Create new broadcast group.
+// Shut it down while a member still reads.
+func TestShutdown(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	group.Send("pending message")
+	received := make(chan interface{}, 2)
+	go func() {
+		received <- member.Recv()
+		received <- member.Recv()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := group.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if val := <-received; val != "pending message" {
+		t.Fatalf("unexpected value %v", val)
+	}
+	if val := <-received; val != EOS {
+		t.Fatalf("expected EOS, got %v", val)
+	}
+	if group.Backlog() != 0 {
+		t.Fatal("backlog must be empty after shutdown")
+	}
+}
+
+// Create new broadcast group with a plain member, an app and a sink.
+// Check that shutdown drains them and closes the sink last.
+func TestShutdownOrder(t *testing.T) {
+	group := NewGroup()
+	plain, app, sink := group.Join(), group.Join(), group.Join()
+	app.SetShutdownOrder(1)
+	sink.SetShutdownOrder(2)
+	go group.Broadcast(0)
+
+	group.Send("pending message")
+	// The sink records whether the app was already closed when the
+	// sink got its own close notice.
+	appClosed := make(chan bool, 1)
+	go func() {
+		for {
+			if _, ok := sink.Recv().(Message); ok {
+				select {
+				case <-app.close:
+					appClosed <- true
+				default:
+					appClosed <- false
+				}
+				return
+			}
+		}
+	}()
+	for _, m := range []*Member{plain, app} {
+		go func(m *Member) {
+			for val := m.Recv(); val != EOS; val = m.Recv() {
+			}
+		}(m)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := group.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if !<-appClosed {
+		t.Fatal("sink closed before the app")
+	}
+	if members := group.Members(); len(members) != 1 || members[0] != plain {
+		t.Fatal("only the member without an order must stay")
+	}
+}
+
+// Create new broadcast group whose loop has stopped.
+// Check that Shutdown gives up when its context is done.
+func TestShutdownStopped(t *testing.T) {
+	group := NewGroup()
+	group.Join()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- group.Run(ctx) }()
+	cancel()
+	<-done
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := group.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("shutdown returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,24 @@
+package bcast
+
+// Snapshot is the membership of a group at one point of its clock.
+type Snapshot struct {
+	// Epoch counts the membership changes of the group.
+	Epoch uint64
+	// Clock is the clock the next broadcast message gets, see
+	// Envelope.Seq.
+	Clock int
+	// Members are the members of the group in the order they joined.
+	Members []*Member
+}
+
+// Snapshot returns the current membership of the group. It is atomic
+// with respect to broadcasts: the messages stamped with Clock or later
+// are fanned out to exactly Members until the next snapshot shows a
+// higher Epoch, and the messages stamped before Clock were not.
+func (g *Group) Snapshot() Snapshot {
+	g.memberLock.Lock()
+	defer g.memberLock.Unlock()
+	members := make([]*Member, len(g.members))
+	copy(members, g.members)
+	return Snapshot{Epoch: g.epoch, Clock: int(g.clock.Load()), Members: members}
+}
@@ -0,0 +1,23 @@
+package bcast
+
+import "testing"
+
+// Create new broadcast group and take snapshots around a broadcast and
+// a membership change.
+func TestSnapshot(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	before := group.Snapshot()
+	group.SendEnvelope(&Envelope{Payload: "stamped"})
+	env, _ := member.RecvEnvelope()
+	if env.Seq != before.Clock {
+		t.Fatalf("message stamped %d, snapshot clock %d", env.Seq, before.Clock)
+	}
+	group.Join()
+	after := group.Snapshot()
+	if after.Epoch != before.Epoch+1 || len(after.Members) != 2 || after.Clock != before.Clock+1 {
+		t.Fatalf("unexpected snapshots %+v and %+v", before, after)
+	}
+}
@@ -0,0 +1,166 @@
+package bcast
+
+import (
+	"context"
+	"flag"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+var soak = flag.Duration("soak", 0, "churn joins, leaves and sends for the given duration in TestSoak")
+
+// Churn members of a broadcast group while messages are sent.
+// Check that no goroutines and no memory are left behind.
+//
+// Without the -soak flag the test runs briefly; run it for hours with
+//
+//	go test -run TestSoak -soak 2h -timeout 3h
+func TestSoak(t *testing.T) {
+	duration := *soak
+	if duration == 0 {
+		if testing.Short() {
+			t.Skip("soak test in short mode")
+		}
+		duration = 200 * time.Millisecond
+	}
+	running, heap := footprint()
+
+	group := NewGroup()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- group.Run(ctx) }()
+	deadline := time.Now().Add(duration)
+	for round := 0; time.Now().Before(deadline); round++ {
+		soakRound(group, rand.Intn(16)+1)
+		if round%1000 == 0 {
+			t.Logf("round %d: %d goroutines", round, runtime.NumGoroutine())
+		}
+	}
+	cancel()
+	<-done
+
+	checkLeaks(t, running, heap)
+}
+
+// soakRound joins members, broadcasts to them while some of them
+// leave early and waits until all of them are gone.
+func soakRound(group *Group, members int) {
+	finished := make(chan bool)
+	for i := 0; i < members; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		member := group.JoinCtx(ctx)
+		leaveAfter := rand.Intn(4)
+		go func() {
+			defer cancel()
+			for i := 0; i < leaveAfter; i++ {
+				select {
+				case <-member.Read:
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+			finished <- true
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		group.Send(i)
+	}
+	for i := 0; i < members; i++ {
+		<-finished
+	}
+}
+
+// footprint returns the goroutines currently running and the heap
+// size after a garbage collection.
+func footprint() (map[string]string, uint64) {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return goroutines(), stats.HeapAlloc
+}
+
+// goroutines returns the stacks of all goroutines keyed by their
+// unique IDs.
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<20)
+	n := runtime.Stack(buf, true)
+	for n == len(buf) {
+		buf = make([]byte, 2*len(buf))
+		n = runtime.Stack(buf, true)
+	}
+	stacks := strings.Split(string(buf[:n]), "\n\n")
+	res := make(map[string]string, len(stacks))
+	for _, stack := range stacks {
+		fields := strings.Fields(stack)
+		if len(fields) < 2 || fields[0] != "goroutine" {
+			continue
+		}
+		res[fields[1]] = stack
+	}
+	return res
+}
+
+// creator returns the ID of the goroutine that started the goroutine
+// with the given stack, or "" for the main goroutine.
+func creator(stack string) string {
+	i := strings.LastIndex(stack, " in goroutine ")
+	if i < 0 {
+		return ""
+	}
+	return strings.Fields(stack[i+len(" in goroutine "):])[0]
+}
+
+// checkLeaks fails the test when goroutines started by the current one
+// after the footprint was taken are still running or when the heap grew
+// noticeably. Goroutines descending from goroutines that were already
+// running belong to other tests and are ignored.
+func checkLeaks(t *testing.T, before map[string]string, heap uint64) {
+	const heapSlack = 4 << 20
+	self := strings.Fields(string(debugStack()))[1]
+	var (
+		leaked      []string
+		currentHeap uint64
+	)
+	for i := 0; i < 100; i++ {
+		var current map[string]string
+		current, currentHeap = footprint()
+		leaked = leaked[:0]
+		for id, stack := range current {
+			if _, ok := before[id]; ok {
+				continue
+			}
+			// Walk up to the first goroutine that was already running.
+			parent := creator(stack)
+			for parent != self {
+				if _, ok := before[parent]; ok {
+					break
+				}
+				if _, ok := current[parent]; !ok {
+					break
+				}
+				parent = creator(current[parent])
+			}
+			if parent == self || before[parent] == "" {
+				leaked = append(leaked, stack)
+			}
+		}
+		if len(leaked) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(leaked) > 0 {
+		t.Fatalf("%d goroutines leaked:\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+	}
+	if currentHeap > heap+heapSlack {
+		t.Fatalf("heap grew from %d to %d bytes", heap, currentHeap)
+	}
+}
+
+// debugStack returns the stack of the current goroutine.
+func debugStack() []byte {
+	buf := make([]byte, 1024)
+	return buf[:runtime.Stack(buf, false)]
+}
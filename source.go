@@ -0,0 +1,35 @@
+package bcast
+
+import "context"
+
+// AddSource broadcasts every value received from ch to the group, as
+// if sent with Send, until ch is closed. Values from one source keep
+// their order, values from several sources are interleaved.
+func (g *Group) AddSource(ch <-chan interface{}) {
+	BroadcastFrom(context.Background(), g, ch)
+}
+
+// BroadcastFrom works like AddSource for channels of any type, such as
+// the channels of time.Tick or signal.Notify, and also stops when ctx
+// is done. Channels of that kind are never closed, so ctx is the only
+// way to stop forwarding from them. The values are broadcast as they
+// are, with their static type T.
+func BroadcastFrom[T any](ctx context.Context, g *Group, ch <-chan T) {
+	go func() {
+		for {
+			select {
+			case val, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case g.in <- g.data(nil, val):
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
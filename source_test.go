@@ -0,0 +1,74 @@
+package bcast
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Create new broadcast group fed by a source channel.
+// Check that the values arrive in order and the pump ends with the
+// source.
+func TestAddSource(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	running, heap := footprint()
+	source := make(chan interface{})
+	group.AddSource(source)
+	for i := 0; i < 3; i++ {
+		source <- i
+	}
+	close(source)
+	for i := 0; i < 3; i++ {
+		if val, err := member.RecvTimeout(time.Second); val != i {
+			t.Fatalf("unexpected value %v (%v)", val, err)
+		}
+	}
+	checkLeaks(t, running, heap)
+}
+
+// Create new broadcast group fed by ticks and signals.
+// Check that both arrive and forwarding stops with the context.
+func TestBroadcastFrom(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	go group.Broadcast(0)
+
+	running, heap := footprint()
+	ctx, cancel := context.WithCancel(context.Background())
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	BroadcastFrom(ctx, group, ticker.C)
+	if val, err := member.RecvTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	} else if _, ok := val.(time.Time); !ok {
+		t.Fatalf("unexpected value %v", val)
+	}
+
+	signals := make(chan os.Signal, 1)
+	BroadcastFrom(ctx, group, signals)
+	signals <- syscall.SIGHUP
+	for {
+		val, err := member.RecvTimeout(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val == syscall.SIGHUP {
+			break
+		}
+	}
+	cancel()
+	// Keep reading so the pumps are not stuck on a delivery.
+	go func() {
+		for {
+			if _, err := member.RecvTimeout(100 * time.Millisecond); err != nil {
+				return
+			}
+		}
+	}()
+	checkLeaks(t, running, heap)
+}
@@ -0,0 +1,94 @@
+package bcast
+
+import "strings"
+
+// Topic is a logical stream carried by a group. Values sent to a topic
+// reach only the members subscribed to it, while values sent to the
+// group itself still reach every member.
+type Topic struct {
+	group *Group
+	name  string
+}
+
+// Topic returns the topic of the group with the given name.
+func (g *Group) Topic(name string) *Topic {
+	return &Topic{group: g, name: name}
+}
+
+// Name returns the name of the topic.
+func (t *Topic) Name() string {
+	return t.name
+}
+
+// Send broadcasts a message to the members of the group subscribed to
+// the topic.
+func (t *Topic) Send(val interface{}) {
+	message := t.group.data(nil, val)
+	message.topic = t.name
+	t.group.in <- message
+}
+
+// Subscribe makes the member receive the values sent to the given
+// topics. Topic names are hierarchical with tokens separated by dots.
+// Like NATS subjects, a subscription may use "*" to match any single
+// token, e.g. "metrics.*" covers "metrics.cpu", and end with ">" to
+// match one or more trailing tokens, e.g. "events.>" covers
+// "events.user.created".
+func (m *Member) Subscribe(topics ...string) {
+	m.topicLock.Lock()
+	defer m.topicLock.Unlock()
+	if m.topics == nil {
+		m.topics = make(map[string]bool)
+	}
+	for _, topic := range topics {
+		m.topics[topic] = true
+	}
+}
+
+// Unsubscribe stops the delivery of the given topics to the member.
+func (m *Member) Unsubscribe(topics ...string) {
+	m.topicLock.Lock()
+	defer m.topicLock.Unlock()
+	for _, topic := range topics {
+		delete(m.topics, topic)
+	}
+}
+
+// subscribed reports whether the member receives messages of topic.
+// Messages sent to the group itself have no topic.
+func (m *Member) subscribed(topic string) bool {
+	if topic == "" {
+		return true
+	}
+	m.topicLock.RLock()
+	defer m.topicLock.RUnlock()
+	if m.topics[topic] {
+		return true
+	}
+	for pattern := range m.topics {
+		if matchTopic(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTopic reports whether the subscription pattern covers topic.
+func matchTopic(pattern, topic string) bool {
+	if !strings.ContainsAny(pattern, "*>") {
+		return pattern == topic
+	}
+	patterns := strings.Split(pattern, ".")
+	tokens := strings.Split(topic, ".")
+	for i, p := range patterns {
+		switch {
+		case p == ">" && i == len(patterns)-1:
+			return len(tokens) > i
+		case i >= len(tokens):
+			return false
+		case p != "*" && p != tokens[i]:
+			return false
+		}
+	}
+	return len(tokens) == len(patterns)
+}
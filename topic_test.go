@@ -0,0 +1,63 @@
+package bcast
+
+import "testing"
+
+// Create new broadcast group with members subscribed to different topics.
+// Send to both topics and to the group itself.
+func TestTopic(t *testing.T) {
+	group := NewGroup()
+	orders := group.Join()
+	orders.Subscribe("orders")
+	payments := group.Join()
+	payments.Subscribe("payments")
+	go group.Broadcast(0)
+
+	go func() {
+		group.Topic("orders").Send("order")
+		group.Topic("payments").Send("payment")
+		group.Send("everyone")
+	}()
+	for _, expected := range []interface{}{"order", "everyone"} {
+		if val := orders.Recv(); val != expected {
+			t.Fatalf("expected %v, got %v", expected, val)
+		}
+	}
+	for _, expected := range []interface{}{"payment", "everyone"} {
+		if val := payments.Recv(); val != expected {
+			t.Fatalf("expected %v, got %v", expected, val)
+		}
+	}
+}
+
+// Match topics against wildcard subscriptions.
+func TestMatchTopic(t *testing.T) {
+	for _, c := range []struct {
+		pattern, topic string
+		match          bool
+	}{
+		{"metrics.*", "metrics.cpu", true},
+		{"metrics.*", "metrics.cpu.user", false},
+		{"metrics.*", "metrics", false},
+		{"events.>", "events.user.created", true},
+		{"events.>", "events", false},
+		{"*.created", "user.created", true},
+		{"orders", "orders", true},
+		{"orders", "orders.new", false},
+	} {
+		if matchTopic(c.pattern, c.topic) != c.match {
+			t.Errorf("matchTopic(%q, %q) != %v", c.pattern, c.topic, c.match)
+		}
+	}
+
+	group := NewGroup()
+	member := group.Join()
+	member.Subscribe("metrics.*")
+	go group.Broadcast(0)
+	go func() {
+		group.Topic("events.user").Send("skipped")
+		group.Topic("metrics.cpu").Send("cpu")
+	}()
+	if val := member.Recv(); val != "cpu" {
+		t.Fatalf("expected cpu, got %v", val)
+	}
+}
@@ -0,0 +1,124 @@
+package bcast
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// TypeError is returned by RecvAs when a received value is not of the
+// expected type.
+type TypeError struct {
+	Value interface{}
+	Want  reflect.Type
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("bcast: received %T, want %v", e.Value, e.Want)
+}
+
+// RecvAs reads one value from the member like Recv and returns it as
+// T. A received error which is not a T, such as EOS or an
+// *ErrorPayload, is returned as the error; any other value of a
+// different type is reported with a *TypeError.
+func RecvAs[T any](m *Member) (T, error) {
+	var zero T
+	val := m.Recv()
+	if typed, ok := val.(T); ok {
+		return typed, nil
+	}
+	if err, ok := val.(error); ok {
+		return zero, err
+	}
+	return zero, &TypeError{Value: val, Want: reflect.TypeOf((*T)(nil)).Elem()}
+}
+
+// SubscribeAs joins group g and returns a channel receiving every value
+// of type T broadcast to it. Values of other types are skipped. The
+// channel is closed after the group reaches the end of stream or once
+// the member leaves, e.g. when it is removed through Members.
+func SubscribeAs[T any](g *Group) <-chan T {
+	member := g.Join()
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			var val interface{}
+			select {
+			case val = <-member.Read:
+			case <-member.close:
+				awaitCloseNotice(member)
+				return
+			}
+			if isCloseNotice(val) {
+				return
+			}
+			if val == EOS {
+				g.leave(member, false)
+				return
+			}
+			if typed, ok := val.(T); ok {
+				out <- typed
+			}
+		}
+	}()
+	return out
+}
+
+// AdaptChan returns an untyped channel to pass to Group.Add so that the
+// typed channel ch receives the values of type T broadcast to the
+// group; values of other types are skipped. The adapter owns the
+// returned channel, which the caller must not close: ch is closed when
+// the group reaches the end of stream or when the member leaves with
+// Leave or Close, whichever comes first. The adapter goes on reading
+// until the member left, so the member never blocks.
+func AdaptChan[T any](ch chan T) chan interface{} {
+	adapter := make(chan interface{})
+	go func() {
+		closed := false
+		for val := range adapter {
+			if isCloseNotice(val) {
+				break
+			}
+			if closed {
+				continue
+			}
+			if val == EOS {
+				close(ch)
+				closed = true
+				continue
+			}
+			if typed, ok := val.(T); ok {
+				ch <- typed
+			}
+		}
+		if !closed {
+			close(ch)
+		}
+	}()
+	return adapter
+}
+
+// isCloseNotice reports whether val is the notice a member receives on
+// Read when it leaves with Leave or Close.
+func isCloseNotice(val interface{}) bool {
+	notice, ok := val.(Message)
+	return ok && notice.msg_type == MSG_TYPE_CLOSE
+}
+
+// awaitCloseNotice takes the close notice of a member which left, so
+// the sender of the notice does not wait for a reader forever. Members
+// leaving without a notice are given up on after weakNotice.
+func awaitCloseNotice(m *Member) {
+	timeout := time.After(weakNotice)
+	for {
+		select {
+		case val := <-m.Read:
+			if isCloseNotice(val) {
+				return
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
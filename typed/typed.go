@@ -0,0 +1,87 @@
+// Package typed wraps bcast groups for a single payload type, so that
+// members get values of that type instead of interface{} to assert.
+package typed
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafov/bcast"
+)
+
+// Group is a broadcast group whose members exchange values of type T.
+type Group[T any] struct {
+	g *bcast.Group
+}
+
+// NewGroup creates a new typed broadcast group configured by opts.
+func NewGroup[T any](opts ...bcast.Option) *Group[T] {
+	return &Group[T]{g: bcast.NewGroup(opts...)}
+}
+
+// Untyped returns the underlying group.
+func (g *Group[T]) Untyped() *bcast.Group {
+	return g.g
+}
+
+// Join returns a new member of the group.
+func (g *Group[T]) Join() *Member[T] {
+	return &Member[T]{m: g.g.Join()}
+}
+
+// Send broadcasts val to every member of the group.
+func (g *Group[T]) Send(val T) {
+	g.g.Send(val)
+}
+
+// MemberCount returns the number of members in the group.
+func (g *Group[T]) MemberCount() int {
+	return g.g.MemberCount()
+}
+
+// Broadcast runs the broadcast loop like bcast.Group.Broadcast.
+func (g *Group[T]) Broadcast(timeout time.Duration) {
+	g.g.Broadcast(timeout)
+}
+
+// Run runs the broadcast loop like bcast.Group.Run.
+func (g *Group[T]) Run(ctx context.Context) error {
+	return g.g.Run(ctx)
+}
+
+// CloseSend ends the stream of the group, members receive bcast.EOS
+// as error afterwards.
+func (g *Group[T]) CloseSend() {
+	g.g.CloseSend()
+}
+
+// Close terminates the broadcast loop.
+func (g *Group[T]) Close() {
+	g.g.Close()
+}
+
+// Member is a member of a typed group.
+type Member[T any] struct {
+	m *bcast.Member
+}
+
+// Untyped returns the underlying member.
+func (m *Member[T]) Untyped() *bcast.Member {
+	return m.m
+}
+
+// Send broadcasts val to the other members of the group.
+func (m *Member[T]) Send(val T) {
+	m.m.Send(val)
+}
+
+// Recv reads one value like bcast.RecvAs. In-band errors and
+// bcast.EOS are returned as the error.
+func (m *Member[T]) Recv() (T, error) {
+	return bcast.RecvAs[T](m.m)
+}
+
+// Close removes the member from its group.
+func (m *Member[T]) Close() {
+	m.m.Close()
+}
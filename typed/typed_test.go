@@ -0,0 +1,26 @@
+package typed
+
+import (
+	"testing"
+
+	"github.com/grafov/bcast"
+)
+
+// Create new typed group with two members.
+// Send a value from one member and receive it typed in the other.
+func TestTypedGroup(t *testing.T) {
+	group := NewGroup[string]()
+	member1 := group.Join()
+	member2 := group.Join()
+	go group.Broadcast(0)
+
+	member1.Send("typed")
+	val, err := member2.Recv()
+	if err != nil || val != "typed" {
+		t.Fatalf("got %q, %v", val, err)
+	}
+	group.CloseSend()
+	if _, err := member2.Recv(); err != bcast.EOS {
+		t.Fatalf("got %v, want EOS", err)
+	}
+}
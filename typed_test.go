@@ -0,0 +1,101 @@
+package bcast
+
+import (
+	"testing"
+	"time"
+)
+
+// Create new broadcast group.
+// Receive values with type checks.
+func TestRecvAs(t *testing.T) {
+	group := NewGroup()
+	member := group.Join()
+	numbers := SubscribeAs[int](group)
+	go group.Broadcast(0)
+
+	go func() {
+		group.Send(1)
+		group.Send("two")
+		group.CloseSend()
+	}()
+	if val, err := RecvAs[int](member); err != nil || val != 1 {
+		t.Fatalf("unexpected result %v, %v", val, err)
+	}
+	if _, err := RecvAs[int](member); err == nil {
+		t.Fatal("type mismatch must be reported")
+	} else if _, ok := err.(*TypeError); !ok {
+		t.Fatalf("expected *TypeError, got %v", err)
+	}
+	if _, err := RecvAs[int](member); err != EOS {
+		t.Fatalf("expected EOS, got %v", err)
+	}
+
+	if val := <-numbers; val != 1 {
+		t.Fatalf("unexpected value %v", val)
+	}
+	if _, ok := <-numbers; ok {
+		t.Fatal("subscription must be closed at the end of stream")
+	}
+}
+
+// Create new broadcast group with a member reading through a typed
+// channel adapter.
+func TestAdaptChan(t *testing.T) {
+	group := NewGroup()
+	numbers := make(chan int)
+	group.Add(AdaptChan(numbers))
+	go group.Broadcast(0)
+
+	go func() {
+		group.Send(1)
+		group.Send("two")
+		group.Send(3)
+		group.CloseSend()
+	}()
+	var got []int
+	for val := range numbers {
+		got = append(got, val)
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("unexpected values %v", got)
+	}
+}
+
+// Create new broadcast group with a member reading through a typed
+// channel adapter and close the member.
+// Check that the adapter closes the typed channel by itself.
+func TestAdaptChanClose(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		group := NewGroup()
+		numbers := make(chan int)
+		member := group.Add(AdaptChan(numbers))
+		go group.Broadcast(0)
+
+		go group.Send(1)
+		if val := <-numbers; val != 1 {
+			t.Fatalf("unexpected value %v", val)
+		}
+		member.Close()
+		for range numbers {
+		}
+		group.Close()
+	}
+}
+
+// Create new broadcast group with a typed subscription and remove its
+// member. Check that the subscription is closed.
+func TestSubscribeAsLeave(t *testing.T) {
+	group := NewGroup()
+	numbers := SubscribeAs[int](group)
+	go group.Broadcast(0)
+
+	group.Members()[0].Close()
+	select {
+	case val, ok := <-numbers:
+		if ok {
+			t.Fatalf("unexpected value %v", val)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription must be closed once the member left")
+	}
+}
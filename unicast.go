@@ -0,0 +1,58 @@
+package bcast
+
+// SendTo sends a message to member only. It is ordered with the
+// broadcasts of the group like any other message: the other members
+// skip it but still advance their clocks past it.
+func (g *Group) SendTo(member *Member, val interface{}) {
+	message := g.data(nil, val)
+	message.to = member
+	g.in <- message
+}
+
+// SendTo sends a message from the member to member only, like
+// Group.SendTo.
+func (m *Member) SendTo(member *Member, val interface{}) {
+	message := m.group.data(m, val)
+	message.to = member
+	m.group.in <- message
+}
+
+// Tag adds tags to the member, addressing it by SendToTagged.
+func (m *Member) Tag(tags ...string) {
+	m.topicLock.Lock()
+	defer m.topicLock.Unlock()
+	if m.tags == nil {
+		m.tags = make(map[string]bool)
+	}
+	for _, tag := range tags {
+		m.tags[tag] = true
+	}
+}
+
+// Untag removes tags from the member.
+func (m *Member) Untag(tags ...string) {
+	m.topicLock.Lock()
+	defer m.topicLock.Unlock()
+	for _, tag := range tags {
+		delete(m.tags, tag)
+	}
+}
+
+// SendToTagged sends a message to the members tagged with tag only.
+// Like SendTo, it is ordered with the broadcasts of the group.
+func (g *Group) SendToTagged(tag string, val interface{}) {
+	message := g.data(nil, val)
+	message.tag = tag
+	g.in <- message
+}
+
+// tagged reports whether the member carries tag. Every member carries
+// the empty tag of untagged messages.
+func (m *Member) tagged(tag string) bool {
+	if tag == "" {
+		return true
+	}
+	m.topicLock.RLock()
+	defer m.topicLock.RUnlock()
+	return m.tags[tag]
+}
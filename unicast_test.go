@@ -0,0 +1,52 @@
+package bcast
+
+import "testing"
+
+// Create new broadcast group with two members.
+// Send a directed message between broadcasts.
+func TestSendTo(t *testing.T) {
+	group := NewGroup()
+	member1 := group.Join()
+	member2 := group.Join()
+	go group.Broadcast(0)
+
+	go func() {
+		group.Send("first")
+		group.SendTo(member2, "direct")
+		member1.SendTo(member2, "from member1")
+		group.Send("last")
+	}()
+	for _, expected := range []string{"first", "last"} {
+		if val := member1.Recv(); val != expected {
+			t.Fatalf("expected %v, got %v", expected, val)
+		}
+	}
+	for _, expected := range []string{"first", "direct", "from member1", "last"} {
+		if val := member2.Recv(); val != expected {
+			t.Fatalf("expected %v, got %v", expected, val)
+		}
+	}
+}
+
+// Create new broadcast group with overlapping tagged members.
+func TestSendToTagged(t *testing.T) {
+	group := NewGroup()
+	gpu := group.Join()
+	gpu.Tag("gpu", "linux")
+	linux := group.Join()
+	linux.Tag("linux")
+	go group.Broadcast(0)
+
+	go func() {
+		group.SendToTagged("gpu", "gpu only")
+		group.SendToTagged("linux", "linux only")
+	}()
+	for _, expected := range []string{"gpu only", "linux only"} {
+		if val := gpu.Recv(); val != expected {
+			t.Fatalf("expected %v, got %v", expected, val)
+		}
+	}
+	if val := linux.Recv(); val != "linux only" {
+		t.Fatalf("unexpected value %v", val)
+	}
+}